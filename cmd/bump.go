@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gardener-community/gardener-chart-releaser/pkg/releaser"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// bumpCmd represents the bump command
+var bumpCmd = &cobra.Command{
+	Use:   "bump [source]...",
+	Short: "Opens pull requests updating the versions in the config file to the latest upstream versions",
+	Long: `This command determines newer upstream versions of the sources, like
+fetchLatestVersions, and proposes them as pull requests against the repository
+holding the config file (see bump in the configuration). By default one pull
+request per source is opened, with --group (or group: true in the configuration)
+all updates are proposed in one pull request. The pull requests contain the
+upstream release notes.
+
+Sources which already have an open bump pull request and pinned sources are
+skipped. Without arguments all sources are bumped, otherwise only the given
+sources. With --dry-run the new versions are only printed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		if viper.GetString("GITHUB_TOKEN") == "" && viper.GetString("GITHUB_APP_ID") == "" {
+			return errors.New("GITHUB_TOKEN is empty")
+		}
+		r, err := newReleaser(cmd)
+		if err != nil {
+			return err
+		}
+		group, _ := cmd.Flags().GetBool("group")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		results, err := r.Bump(cmd.Context(), releaser.BumpOptions{
+			Sources: args,
+			Group:   group,
+			DryRun:  dryRun,
+		})
+		for _, result := range results {
+			switch {
+			case result.Skipped != "":
+				logrus.Info("Skipping ", result.Source, " ", result.From, ": ", result.Skipped)
+			case result.To != "":
+				fmt.Fprintf(cmd.OutOrStdout(), "%s: %s -> %s %s\n", result.Source, result.From, result.To, result.PullRequestURL)
+			}
+		}
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(bumpCmd)
+	bumpCmd.Flags().Bool("group", false, "Propose all version updates in one pull request")
+	bumpCmd.Flags().Bool("dry-run", false, "Only print the version updates without opening pull requests")
+	bumpCmd.Flags().Int("concurrency", 4, "The number of sources which are processed in parallel")
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// compatCmd represents the compat command
+var compatCmd = &cobra.Command{
+	Use:   "compat [source]...",
+	Short: "Checks the configured versions for breaking changes against the published versions",
+	Long: `This command builds the configured version of the sources and compares it to
+the latest version published in the destination repository which is older.
+The values, the kinds and names of the resources rendered with the default
+values and the CRDs are compared, and the changes are classified as breaking
+(e.g. removed values or resources) or additive.
+
+The command fails if a source has breaking changes although its major version
+was not bumped. Without arguments all sources are checked, otherwise only the
+given sources.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		r, err := newReleaser(cmd)
+		if err != nil {
+			return err
+		}
+
+		results, err := r.Compat(cmd.Context(), args)
+		for _, result := range results {
+			if result.Compat == nil {
+				if result.Error == "" {
+					logrus.Info(result.Source, " ", result.Version, ": no previous version published")
+				}
+				continue
+			}
+			logrus.Info(result.Source, " ", result.Version, ": ", len(result.Compat.Breaking), " breaking and ",
+				len(result.Compat.Additive), " additive changes since ", result.Compat.From)
+			for _, c := range result.Compat.Breaking {
+				logrus.Warn("  breaking: ", c)
+			}
+			for _, c := range result.Compat.Additive {
+				logrus.Info("  additive: ", c)
+			}
+			for _, w := range result.Compat.Warnings {
+				logrus.Warn("  ", w)
+			}
+		}
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(compatCmd)
+	compatCmd.Flags().Int("concurrency", 4, "The number of sources which are processed in parallel")
+}
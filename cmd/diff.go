@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/gardener-community/gardener-chart-releaser/pkg/releaser"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff <source> <fromVersion> <toVersion>",
+	Short: "Prints the differences of the rendered manifests of two versions of a chart",
+	Long: `This command builds two versions of a source, as update would release them,
+renders both with the same values and prints a unified diff per resource which
+was added, removed or changed. Values files given with -f are merged into the
+default values, later files take precedence.
+
+The versions are upstream tags, e.g.
+
+  gardener-chart-releaser diff gardener-controlplane v1.53.0 v1.54.0 -f values.yaml`,
+	Args: cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		r, err := newReleaser(cmd)
+		if err != nil {
+			return err
+		}
+		valuesFiles, _ := cmd.Flags().GetStringArray("values")
+		namespace, _ := cmd.Flags().GetString("namespace")
+
+		diff, err := r.Diff(cmd.Context(), releaser.DiffOptions{
+			Source:      args[0],
+			From:        args[1],
+			To:          args[2],
+			ValuesFiles: valuesFiles,
+			Namespace:   namespace,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(cmd.OutOrStdout(), diff.Unified())
+		logrus.Info(len(diff.Resources), " resources differ between ", diff.From, " and ", diff.To)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().StringArrayP("values", "f", nil, "A values file both versions are rendered with, can be given multiple times")
+	diffCmd.Flags().String("namespace", "default", "The namespace of the release")
+}
@@ -5,9 +5,8 @@ Copyright © 2022 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
-	"github.com/gardener-community/gardener-chart-releaser/pkg/releaser"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 // exportCmd represents the export command
@@ -21,18 +20,27 @@ also make sense for others you can go ahead and file pull requests in
 the corresponding upstream repository.
 
 This command requires the environmet variable GITHUB_TOKEN to be set.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 
-		config := releaser.Configuration{}
-		viper.Unmarshal(&config)
-		ghToken := viper.GetString("GITHUB_TOKEN")
-		targetDir := viper.GetString("targetDir")
+		r, err := newReleaser(cmd)
+		if err != nil {
+			return err
+		}
 
-		releaser.ExportCharts(config, targetDir, ghToken)
+		report, err := r.Export(cmd.Context())
+		if reportErr := writeReport(cmd, report); reportErr != nil {
+			logrus.Error(reportErr)
+			if err == nil {
+				err = reportErr
+			}
+		}
+		return err
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(exportCmd)
 	exportCmd.Flags().String("targetDir", "charts", "The directory where charts are stored locally")
+	exportCmd.Flags().Int("concurrency", 4, "The number of sources which are processed in parallel")
+	addReportFlags(exportCmd)
 }
@@ -1,15 +1,14 @@
 package cmd
 
 import (
-	"context"
-	"log"
-	"strings"
+	"errors"
+	"fmt"
+	"os"
 
 	"github.com/gardener-community/gardener-chart-releaser/pkg/releaser"
-	"github.com/google/go-github/v36/github"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"golang.org/x/oauth2"
 )
 
 // fetchLatestVersionsCmd represents the fetchLatestVersions command
@@ -19,40 +18,63 @@ var fetchLatestVersionsCmd = &cobra.Command{
 	Long: `This is a utility command for updating all versions specified in config.yaml.
 It comes handy, when charts are exported for development purposes and one wants to
 export the most recent version.
+
+Sources with a version constraint are updated to the latest release satisfying it,
+pinned sources are skipped. Only the versions are replaced in the config file, its
+comments and formatting are kept. With --dry-run the changes are only printed.
 `,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 
-		config := releaser.Configuration{}
-		viper.Unmarshal(&config)
-		ghToken := viper.GetString("GITHUB_TOKEN")
-		if ghToken == "" {
-			log.Fatal("GITHUB_TOKEN is empty")
+		if viper.GetString("GITHUB_TOKEN") == "" && viper.GetString("GITHUB_APP_ID") == "" {
+			return errors.New("GITHUB_TOKEN is empty")
 		}
-
-		// get a *github.Client	for the github token
-		// this client will be used for interacting with the github api
-		ts := oauth2.StaticTokenSource(
-			&oauth2.Token{AccessToken: ghToken},
-		)
-		tokenClient := oauth2.NewClient(context.Background(), ts)
-		client := github.NewClient(tokenClient)
+		r, err := newReleaser(cmd)
+		if err != nil {
+			return err
+		}
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
 
 		// main loop over all items in the config file
-		for i, cfg := range config.SrcCfg {
-			owner := strings.Split(cfg.Repo, "/")[0]
-			repo := strings.Split(cfg.Repo, "/")[1]
-			latestRelease, _, err := client.Repositories.GetLatestRelease(context.Background(), owner, repo)
+		// sources which fail keep their current version
+		var errs releaser.Errors
+		versions := map[string]string{}
+		for _, cfg := range r.Config().SrcCfg {
+			if cfg.Pinned {
+				logrus.Info("Skipping pinned source ", cfg.Name, " ", cfg.Version)
+				continue
+			}
+			version, err := r.LatestVersion(cmd.Context(), cfg)
 			if err != nil {
-				log.Fatal(err)
+				logrus.Error(err)
+				errs = append(errs, &releaser.SourceError{Source: cfg.Name, Err: err})
+				continue
+			}
+			if version != cfg.Version {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s: %s -> %s\n", cfg.Name, cfg.Version, version)
+				versions[cfg.Name] = version
 			}
-			config.SrcCfg[i].Version = *latestRelease.TagName
 		}
-		viper.Set("sources", config.SrcCfg)
-		viper.WriteConfig()
+		if dryRun || len(versions) == 0 {
+			return errs.ErrorOrNil()
+		}
+
+		path := viper.ConfigFileUsed()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading configuration: %w", err)
+		}
+		data, err = releaser.SetSourceVersions(data, versions)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return fmt.Errorf("writing configuration: %w", err)
+		}
+		return errs.ErrorOrNil()
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(fetchLatestVersionsCmd)
-
+	fetchLatestVersionsCmd.Flags().Bool("dry-run", false, "Only print the version changes without writing the config file")
 }
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// lintCmd represents the lint command
+var lintCmd = &cobra.Command{
+	Use:   "lint [source]...",
+	Short: "Lints the charts of the configured versions",
+	Long: `This command builds the charts of the configured versions, as update would
+release them, and runs the helm linters on them. The charts are rendered with
+their default values and with all dependencies enabled. Render errors, images
+tagged latest and empty templates fail the lint.
+
+Without arguments all sources are linted, otherwise only the given sources.
+Use update --lint to lint the charts before publishing them.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		r, err := newReleaser(cmd)
+		if err != nil {
+			return err
+		}
+
+		results, err := r.Lint(cmd.Context(), args)
+		for _, result := range results {
+			for _, m := range result.Messages {
+				logrus.Warn(result.Source, " ", result.Version, ": ", m)
+			}
+			if result.Error == "" {
+				logrus.Info(result.Source, " ", result.Version, " passed the lint")
+			}
+		}
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+	lintCmd.Flags().Int("concurrency", 4, "The number of sources which are processed in parallel")
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"github.com/gardener-community/gardener-chart-releaser/pkg/releaser"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// reconcileCmd represents the reconcile command
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Reconciles the index.yaml of the destination repository with its releases (requires GITHUB_TOKEN)",
+	Long: `If creating releases succeeded but updating the index failed, the destination
+repository contains releases which are not listed in its index.yaml. This command
+compares the chart packages attached to the GitHub releases of the destination
+repository with the index, adds missing entries (including their digests) and
+reports entries whose release assets do not exist anymore.
+
+With --prune these stale entries are removed, with --rebuild the index is
+created from scratch from the release assets.
+
+This command requires the environment variable GITHUB_TOKEN to be set.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		r, err := newReleaser(cmd)
+		if err != nil {
+			return err
+		}
+
+		rebuild, _ := cmd.Flags().GetBool("rebuild")
+		prune, _ := cmd.Flags().GetBool("prune")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		result, err := r.Reconcile(cmd.Context(), releaser.ReconcileOptions{
+			Rebuild: rebuild,
+			Prune:   prune,
+			DryRun:  dryRun,
+		})
+		if err != nil {
+			return err
+		}
+		logrus.Info("Added ", len(result.Added), " entries, found ", len(result.Stale), " stale entries")
+		if result.PullRequestURL != "" {
+			logrus.Info("The reconciled index is proposed in ", result.PullRequestURL)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reconcileCmd)
+	reconcileCmd.Flags().Bool("rebuild", false, "Rebuild the index from scratch from the release assets")
+	reconcileCmd.Flags().Bool("prune", false, "Remove index entries whose release assets do not exist anymore")
+	reconcileCmd.Flags().Bool("dry-run", false, "Only report differences, do not push the index")
+}
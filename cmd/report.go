@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/gardener-community/gardener-chart-releaser/pkg/releaser"
+	"github.com/spf13/cobra"
+)
+
+// addReportFlags adds the flags for writing a run report to cmd
+func addReportFlags(cmd *cobra.Command) {
+	cmd.Flags().String("report-json", "", "Write a JSON report of the run to this file")
+	cmd.Flags().String("report-markdown", "", "Write a Markdown report of the run to this file")
+}
+
+// writeReport writes the report to the files given by the report flags of cmd
+func writeReport(cmd *cobra.Command, report *releaser.Report) error {
+	if path, _ := cmd.Flags().GetString("report-json"); path != "" {
+		if err := report.WriteJSON(path); err != nil {
+			return fmt.Errorf("writing JSON report: %w", err)
+		}
+	}
+	if path, _ := cmd.Flags().GetString("report-markdown"); path != "" {
+		if err := report.WriteMarkdown(path); err != nil {
+			return fmt.Errorf("writing Markdown report: %w", err)
+		}
+	}
+	return nil
+}
@@ -1,15 +1,32 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/gardener-community/gardener-chart-releaser/pkg/releaser"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var cfgFile string
 
+// exit codes of the binary
+const (
+	// exitCodeError signals that the run could not be completed
+	exitCodeError = 1
+	// exitCodeChartsFailed signals that the run was completed, but at least one chart failed
+	exitCodeChartsFailed = 2
+	// exitCodeOutdated signals that status found outdated sources
+	exitCodeOutdated = 3
+)
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "gardener-chart-releaser",
@@ -19,14 +36,38 @@ all containing helm charts on their own. This program collects these charts from
 several sources (see sources in config.yaml) and releases them in a destination
 github repository. The destination repository also serves as helm repository via
 github pages.`,
+	// errors are reported once by Execute
+	SilenceErrors: true,
+	SilenceUsage:  true,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	err := rootCmd.Execute()
+	// the first interrupt cancels the running command, which cleans up its workspaces,
+	// a second one terminates the process immediately
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		logrus.Warn("Interrupted, cleaning up. Interrupt again to exit immediately")
+		signal.Stop(signals)
+		cancel()
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
 	if err != nil {
-		os.Exit(1)
+		logrus.Error(err)
+		var errs releaser.Errors
+		if errors.As(err, &errs) {
+			os.Exit(exitCodeChartsFailed)
+		}
+		if errors.Is(err, errOutdated) {
+			os.Exit(exitCodeOutdated)
+		}
+		os.Exit(exitCodeError)
 	}
 }
 
@@ -34,9 +75,76 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "config.yaml", "config file")
+	rootCmd.PersistentFlags().Duration("timeout", 0, "The maximum duration of the whole run, 0 means no limit")
+	rootCmd.PersistentFlags().Duration("request-timeout", 2*time.Minute, "The maximum duration of a single HTTP request")
+	rootCmd.PersistentFlags().Duration("git-timeout", 10*time.Minute, "The maximum duration of a single clone, fetch or push")
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
+// newReleaser creates a Releaser from the configuration file, the flags of cmd and the environment
+func newReleaser(cmd *cobra.Command) (*releaser.Releaser, error) {
+	config := releaser.Configuration{}
+	if err := viper.Unmarshal(&config); err != nil {
+		return nil, fmt.Errorf("reading configuration: %w", err)
+	}
+	targetDir := viper.GetString("targetDir")
+	if cmd.Flags().Lookup("targetDir") != nil {
+		targetDir, _ = cmd.Flags().GetString("targetDir")
+	}
+	concurrency := 1
+	if cmd.Flags().Lookup("concurrency") != nil {
+		concurrency, _ = cmd.Flags().GetInt("concurrency")
+	}
+	lint := false
+	if cmd.Flags().Lookup("lint") != nil {
+		lint, _ = cmd.Flags().GetBool("lint")
+	}
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	requestTimeout, _ := cmd.Flags().GetDuration("request-timeout")
+	gitTimeout, _ := cmd.Flags().GetDuration("git-timeout")
+	app, err := githubApp()
+	if err != nil {
+		return nil, err
+	}
+	return releaser.New(releaser.Options{
+		Config:         config,
+		TargetDir:      targetDir,
+		Concurrency:    concurrency,
+		Lint:           lint,
+		Timeout:        timeout,
+		RequestTimeout: requestTimeout,
+		GitTimeout:     gitTimeout,
+		Token:          viper.GetString("GITHUB_TOKEN"),
+		App:            app,
+	})
+}
+
+// githubApp returns the GitHub App configured by GITHUB_APP_ID, GITHUB_APP_INSTALLATION_ID
+// and GITHUB_APP_PRIVATE_KEY_FILE, or nil if no app is configured. The app is installed on the
+// host of the destination, its tokens are not used for other hosts.
+func githubApp() (*releaser.GitHubApp, error) {
+	if viper.GetString("GITHUB_APP_ID") == "" {
+		return nil, nil
+	}
+	app := &releaser.GitHubApp{
+		AppID:          viper.GetInt64("GITHUB_APP_ID"),
+		InstallationID: viper.GetInt64("GITHUB_APP_INSTALLATION_ID"),
+	}
+	if app.AppID == 0 || app.InstallationID == 0 {
+		return nil, errors.New("GITHUB_APP_ID and GITHUB_APP_INSTALLATION_ID must be numeric ids")
+	}
+	keyFile := viper.GetString("GITHUB_APP_PRIVATE_KEY_FILE")
+	if keyFile == "" {
+		return nil, errors.New("GITHUB_APP_PRIVATE_KEY_FILE is empty")
+	}
+	var err error
+	app.PrivateKey, err = os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading private key of GitHub App: %w", err)
+	}
+	return app, nil
+}
+
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
 	if cfgFile != "" {
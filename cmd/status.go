@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// errOutdated is returned by status --fail-if-outdated if a source is outdated
+var errOutdated = errors.New("sources are outdated")
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status [source]...",
+	Short: "Shows the configured, upstream and published versions of all sources",
+	Long: `This command gives an overview of the drift between the configuration, upstream
+and the destination repository. For each source it lists the version in the
+config file, the latest upstream release (respecting the constraint of the
+source), the latest version published in the index of the destination repository
+and the number of upstream releases the configured version is behind.
+
+A source is outdated if it is behind upstream or its configured version is not
+published yet. With --fail-if-outdated the command exits with code 3 then.
+Without arguments all sources are listed, otherwise only the given sources.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		r, err := newReleaser(cmd)
+		if err != nil {
+			return err
+		}
+		output, _ := cmd.Flags().GetString("output")
+		failIfOutdated, _ := cmd.Flags().GetBool("fail-if-outdated")
+		if output != "table" && output != "json" {
+			return fmt.Errorf("unknown output format %q, expected table or json", output)
+		}
+
+		statuses, err := r.Status(cmd.Context(), args)
+		if statuses == nil && err != nil {
+			return err
+		}
+		if output == "json" {
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			if encErr := enc.Encode(statuses); encErr != nil {
+				return encErr
+			}
+		} else {
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "SOURCE\tCONFIGURED\tLATEST UPSTREAM\tLATEST PUBLISHED\tBEHIND\tERROR")
+			for _, s := range statuses {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", s.Source, s.Configured, s.LatestUpstream, s.LatestPublished, s.Behind, s.Error)
+			}
+			if flushErr := w.Flush(); flushErr != nil {
+				return flushErr
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		if failIfOutdated {
+			for _, s := range statuses {
+				if s.Outdated() {
+					return errOutdated
+				}
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().StringP("output", "o", "table", "The output format, table or json")
+	statusCmd.Flags().Bool("fail-if-outdated", false, "Exit with code 3 if a source is outdated")
+	statusCmd.Flags().Int("concurrency", 4, "The number of sources which are processed in parallel")
+}
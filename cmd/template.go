@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"github.com/gardener-community/gardener-chart-releaser/pkg/releaser"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// templateCmd represents the template command
+var templateCmd = &cobra.Command{
+	Use:   "template [source]...",
+	Short: "Renders the charts of the configured versions with landscape values",
+	Long: `This command builds the charts of the configured versions in memory and renders
+them like helm template does. Values files given with -f are merged into the
+default values of the charts, later files take precedence. Dependencies are
+rendered according to their conditions, e.g. controller.enabled for the charts
+of extensions.
+
+The manifests of each chart are written to <output-dir>/<source>.yaml, or with
+--split-by-kind to one file per kind in <output-dir>/<source>/. Without
+arguments all sources are rendered, otherwise only the given sources.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		r, err := newReleaser(cmd)
+		if err != nil {
+			return err
+		}
+		valuesFiles, _ := cmd.Flags().GetStringArray("values")
+		outputDir, _ := cmd.Flags().GetString("output-dir")
+		split, _ := cmd.Flags().GetBool("split-by-kind")
+		namespace, _ := cmd.Flags().GetString("namespace")
+		includeCRDs, _ := cmd.Flags().GetBool("include-crds")
+
+		results, err := r.Template(cmd.Context(), releaser.TemplateOptions{
+			Sources:     args,
+			ValuesFiles: valuesFiles,
+			OutputDir:   outputDir,
+			SplitByKind: split,
+			Namespace:   namespace,
+			IncludeCRDs: includeCRDs,
+		})
+		for _, result := range results {
+			for _, f := range result.Files {
+				logrus.Info(result.Source, " ", result.Version, ": wrote ", f)
+			}
+		}
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(templateCmd)
+	templateCmd.Flags().StringArrayP("values", "f", nil, "A values file of the landscape, can be given multiple times")
+	templateCmd.Flags().String("output-dir", "rendered", "The directory the manifests are written to")
+	templateCmd.Flags().Bool("split-by-kind", false, "Write one file per Kubernetes kind and chart")
+	templateCmd.Flags().String("namespace", "default", "The namespace of the releases")
+	templateCmd.Flags().Bool("include-crds", false, "Include the CRDs of the charts in the manifests")
+	templateCmd.Flags().Int("concurrency", 4, "The number of sources which are processed in parallel")
+}
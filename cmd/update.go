@@ -3,8 +3,8 @@ package cmd
 import (
 	"fmt"
 
-	"github.com/gardener-community/gardener-chart-releaser/pkg/releaser"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -22,15 +22,21 @@ Generally, the program will check, whether releases on the source side exist,
 which are not availabe on the destination side, yet. If so, the missing releases
 will be created. As of now, only releases will be tracked that are younger than the
 maximum minor version minus 3.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 
-		config := releaser.Configuration{}
-		viper.Unmarshal(&config) 
-
-		ghToken := viper.GetString("GITHUB_TOKEN")
-		targetDir := viper.GetString("targetDir")
+		r, err := newReleaser(cmd)
+		if err != nil {
+			return err
+		}
 
-		releaser.UpdateReleases(config, targetDir, ghToken)
+		report, err := r.Update(cmd.Context())
+		if reportErr := writeReport(cmd, report); reportErr != nil {
+			logrus.Error(reportErr)
+			if err == nil {
+				err = reportErr
+			}
+		}
+		return err
 	},
 }
 
@@ -38,6 +44,9 @@ func init() {
 	rootCmd.AddCommand(updateCmd)
 
 	updateCmd.Flags().String("targetDir", "charts", "The directory where charts are stored locally")
+	updateCmd.Flags().Int("concurrency", 4, "The number of sources which are processed in parallel")
+	updateCmd.Flags().Bool("lint", false, "Lint the charts before publishing them, charts with lint errors are not published")
+	addReportFlags(updateCmd)
 
 	// add flags to viper according to
 	// https://github.com/helm/chart-releaser/blob/main/pkg/config/config.go
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"github.com/gardener-community/gardener-chart-releaser/pkg/releaser"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate [source]...",
+	Short: "Validates the rendered charts against Kubernetes and Gardener schemas",
+	Long: `This command builds the charts of the configured versions, renders them with
+all dependencies enabled and validates the manifests against the JSON schemas of
+the Kubernetes versions of the configuration (validation.kubernetesVersions, or
+--kubernetes-version). Gardener resources like ControllerRegistrations and
+ControllerDeployments are validated against bundled schemas.
+
+Kubernetes schemas are downloaded into the cache directory, the validation fails
+if they cannot be downloaded. With --offline only bundled and cached schemas are
+used; as Kubernetes schemas are not bundled, an online run has to fill the cache
+first, offline runs fail for Kubernetes versions without cached schemas. Without arguments all sources are validated, otherwise only the given
+sources.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		r, err := newReleaser(cmd)
+		if err != nil {
+			return err
+		}
+		versions, _ := cmd.Flags().GetStringSlice("kubernetes-version")
+		offline, _ := cmd.Flags().GetBool("offline")
+
+		results, err := r.Validate(cmd.Context(), releaser.ValidateOptions{
+			Sources:            args,
+			KubernetesVersions: versions,
+			Offline:            offline,
+		})
+		for _, result := range results {
+			for _, w := range result.Warnings {
+				logrus.Warn(result.Source, " ", result.Version, ": ", w)
+			}
+			for _, e := range result.Errors {
+				logrus.Error(result.Source, " ", result.Version, ": ", e)
+			}
+			if result.Error == "" {
+				logrus.Info(result.Source, " ", result.Version, " is valid")
+			}
+		}
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+	validateCmd.Flags().StringSlice("kubernetes-version", nil, "The Kubernetes versions to validate for, overrides the configuration")
+	validateCmd.Flags().Bool("offline", false, "Only use bundled and cached schemas")
+	validateCmd.Flags().Int("concurrency", 4, "The number of sources which are processed in parallel")
+}
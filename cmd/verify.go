@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"github.com/gardener-community/gardener-chart-releaser/pkg/releaser"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify [chart|chart-version]...",
+	Short: "Verifies the charts published in the destination repository",
+	Long: `This command downloads the chart packages listed in the index.yaml of the
+destination repository and checks that their release assets exist and their
+digests match the index. If a keyring is given (it defaults to the keyring of
+the signing configuration), the provenance files published next to the packages
+are verified as well.
+
+With --rebuild the published versions of configured sources are rebuilt from
+upstream and compared file by file with the published packages, which reveals
+tampered packages and drift in the build pipeline.
+
+Without arguments all charts of the index are verified, otherwise only the
+given charts (e.g. gardener-controlplane) or chart versions (e.g.
+gardener-controlplane-1.53.0).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		r, err := newReleaser(cmd)
+		if err != nil {
+			return err
+		}
+
+		keyring, _ := cmd.Flags().GetString("keyring")
+		rebuild, _ := cmd.Flags().GetBool("rebuild")
+		if keyring == "" {
+			keyring = viper.GetString("signing.keyring")
+		}
+
+		results, err := r.Verify(cmd.Context(), releaser.VerifyOptions{
+			Keyring: keyring,
+			Charts:  args,
+			Rebuild: rebuild,
+		})
+		for _, result := range results {
+			if result.SignedBy != "" {
+				logrus.Info(result.Name, "-", result.Version, " is signed by ", result.SignedBy)
+			}
+			if result.Rebuilt && len(result.Differences) == 0 && result.Error == "" {
+				logrus.Info(result.Name, "-", result.Version, " matches the package rebuilt from upstream")
+			}
+			for _, d := range result.Differences {
+				logrus.Warn("  ", d)
+			}
+		}
+		logrus.Info("Verified ", len(results), " chart versions")
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+	verifyCmd.Flags().String("keyring", "", "The keyring with the public keys to verify the provenance files with")
+	verifyCmd.Flags().Bool("rebuild", false, "Rebuild the published versions from upstream and compare them with the published packages")
+}
@@ -0,0 +1,115 @@
+package releaser
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// GitHubApp identifies the installation of a GitHub App, which is used instead of a token
+type GitHubApp struct {
+	AppID          int64
+	InstallationID int64
+	// PrivateKey is the PEM encoded private key of the app
+	PrivateKey []byte
+}
+
+// appTokenSource mints installation tokens of a GitHub App
+type appTokenSource struct {
+	ctx       context.Context
+	app       GitHubApp
+	key       *rsa.PrivateKey
+	endpoints Endpoints
+}
+
+// NewAppTokenSource returns a token source which mints installation tokens of the app at the API endpoint.
+// Tokens are reused until shortly before they expire and refreshed afterwards.
+func NewAppTokenSource(ctx context.Context, app GitHubApp, endpoints Endpoints) (oauth2.TokenSource, error) {
+	key, err := parsePrivateKey(app.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("parsing private key of GitHub App %d: %w", app.AppID, err)
+	}
+	return oauth2.ReuseTokenSource(nil, &appTokenSource{
+		ctx:       ctx,
+		app:       app,
+		key:       key,
+		endpoints: endpoints.withDefaults(),
+	}), nil
+}
+
+func (s *appTokenSource) Token() (*oauth2.Token, error) {
+	jwt, err := s.jwt(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	// the app itself authenticates with a JWT, which is only valid for minting installation tokens
+	client, err := NewGitHubClient(s.ctx, jwt, s.endpoints)
+	if err != nil {
+		return nil, err
+	}
+	token, _, err := client.Apps.CreateInstallationToken(s.ctx, s.app.InstallationID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating installation token of GitHub App %d: %w", s.app.AppID, err)
+	}
+	// refresh a bit early, so that tokens do not expire in the middle of a push
+	return &oauth2.Token{
+		AccessToken: token.GetToken(),
+		Expiry:      token.GetExpiresAt().Add(-time.Minute),
+	}, nil
+}
+
+// jwt returns the signed JSON web token of the app
+func (s *appTokenSource) jwt(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	// GitHub accepts tokens which are valid for at most ten minutes, issue them in
+	// the past to allow for clock drift
+	claims, err := json.Marshal(map[string]int64{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": s.app.AppID,
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parsePrivateKey parses a PEM encoded PKCS#1 or PKCS#8 RSA private key
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA private key")
+	}
+	return rsaKey, nil
+}
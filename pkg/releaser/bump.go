@@ -0,0 +1,329 @@
+package releaser
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/google/go-github/v36/github"
+)
+
+const (
+	bumpBranchPrefix = "chart-releaser/bump-"
+	defaultBumpBase  = "main"
+	defaultBumpPath  = "config.yaml"
+)
+
+// bumpMarker marks the sources a bump pull request updates in its body
+var bumpMarker = regexp.MustCompile(`<!-- chart-releaser-bump: (\S+) -->`)
+
+// BumpOptions controls which sources Bump updates and how
+type BumpOptions struct {
+	// Sources restricts the bump to these sources, all sources are bumped if it is empty
+	Sources []string
+	// Group proposes all updates in one pull request, in addition to the configuration
+	Group bool
+	// DryRun only determines the new versions without opening pull requests
+	DryRun bool
+}
+
+// BumpResult describes the version update of a source
+type BumpResult struct {
+	Source string `json:"source"`
+	From   string `json:"from"`
+	To     string `json:"to,omitempty"`
+	// Skipped is the reason the source was not bumped, e.g. because it is up to date
+	Skipped        string `json:"skipped,omitempty"`
+	PullRequestURL string `json:"pullRequestURL,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// bump is a source which is updated to a newer version
+type bump struct {
+	cfg    SrcConfiguration
+	result *BumpResult
+}
+
+// Bump determines newer upstream versions of the sources and proposes them as pull requests against
+// the repository holding the configuration, one per source or one for all sources. Sources with an
+// open bump pull request are skipped. Sources which could not be bumped are returned as Errors.
+func (r *Releaser) Bump(ctx context.Context, opts BumpOptions) ([]*BumpResult, error) {
+	cfg := r.opts.Config.Bump
+	if cfg == nil {
+		return nil, errors.New("no bump repository configured")
+	}
+	if hostKey(cfg.Host) != hostKey(r.opts.Config.DstCfg.Host) {
+		// the token is only sent to the host of the destination repository
+		return nil, errors.New("the bump repository has to be on the host of the destination repository")
+	}
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	dir, err := r.cloneBumpRepo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.opts.FileSystem.RemoveAll(dir)
+	data, err := r.opts.FileSystem.ReadFile(filepath.Join(dir, bumpPath(cfg)))
+	if err != nil {
+		return nil, fmt.Errorf("reading configuration of %s/%s: %w", cfg.Owner, cfg.Repo, err)
+	}
+	current, err := SourceVersions(data)
+	if err != nil {
+		return nil, err
+	}
+	open, err := r.openBumps(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*BumpResult, len(r.opts.Config.SrcCfg))
+	errs := make([]error, len(r.opts.Config.SrcCfg))
+	r.forEachSource(ctx, func(i int, src SrcConfiguration) {
+		if len(opts.Sources) > 0 && !contains(opts.Sources, src.Name) {
+			return
+		}
+		result := &BumpResult{Source: src.Name, From: src.Version}
+		if v, ok := current[src.Name]; ok {
+			result.From = v
+		}
+		results[i] = result
+		switch {
+		case src.Pinned:
+			result.Skipped = "pinned"
+		case open[src.Name] != "":
+			result.Skipped = "bump pull request " + open[src.Name] + " is open"
+		default:
+			result.To, errs[i] = r.newerVersion(ctx, src, result.From)
+			if errs[i] == nil && result.To == "" {
+				result.Skipped = "up to date"
+			}
+		}
+	})
+
+	var bumps []bump
+	var checked []*BumpResult
+	var sourceErrs Errors
+	for i, result := range results {
+		if result == nil {
+			continue
+		}
+		checked = append(checked, result)
+		if errs[i] != nil {
+			result.Error = errs[i].Error()
+			sourceErrs = append(sourceErrs, &SourceError{Source: result.Source, Err: errs[i]})
+			continue
+		}
+		if result.To != "" {
+			src := r.opts.Config.SrcCfg[i]
+			src.Version = result.To
+			bumps = append(bumps, bump{cfg: src, result: result})
+		}
+	}
+	if ctx.Err() != nil {
+		return checked, fmt.Errorf("bump interrupted: %w", ctx.Err())
+	}
+	if opts.DryRun || len(bumps) == 0 {
+		return checked, sourceErrs.ErrorOrNil()
+	}
+
+	if opts.Group || cfg.Group {
+		// the base branch is checked out already, so the clone can be reused
+		if err := r.proposeBump(ctx, dir, data, bumps); err != nil {
+			for _, b := range bumps {
+				b.result.Error = err.Error()
+				sourceErrs = append(sourceErrs, &SourceError{Source: b.result.Source, Version: b.result.To, Err: err})
+			}
+		}
+		return checked, sourceErrs.ErrorOrNil()
+	}
+	for _, b := range bumps {
+		err := r.proposeSourceBump(ctx, b)
+		if err != nil {
+			b.result.Error = err.Error()
+			sourceErrs = append(sourceErrs, &SourceError{Source: b.result.Source, Version: b.result.To, Err: err})
+		}
+	}
+	return checked, sourceErrs.ErrorOrNil()
+}
+
+// newerVersion returns the latest upstream version of a source if it is newer than current, otherwise ""
+func (r *Releaser) newerVersion(ctx context.Context, cfg SrcConfiguration, current string) (string, error) {
+	latest, err := r.LatestVersion(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+	latestVersion, err := semver.NewVersion(latest)
+	if err != nil {
+		return "", fmt.Errorf("parsing upstream release %s of %s: %w", latest, cfg.Repo, err)
+	}
+	currentVersion, err := semver.NewVersion(current)
+	if err == nil && !latestVersion.GreaterThan(currentVersion) {
+		return "", nil
+	}
+	return latest, nil
+}
+
+// openBumps returns the URLs of the open bump pull requests by the sources they update
+func (r *Releaser) openBumps(ctx context.Context) (map[string]string, error) {
+	cfg := r.opts.Config.Bump
+	open := map[string]string{}
+	opts := &github.PullRequestListOptions{State: "open", ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		pulls, resp, err := r.host(cfg.Host).pulls.List(ctx, cfg.Owner, cfg.Repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("listing pull requests of %s/%s: %w", cfg.Owner, cfg.Repo, err)
+		}
+		for _, pull := range pulls {
+			if !strings.HasPrefix(pull.GetHead().GetRef(), bumpBranchPrefix) {
+				continue
+			}
+			for _, m := range bumpMarker.FindAllStringSubmatch(pull.GetBody(), -1) {
+				open[m[1]] = pull.GetHTMLURL()
+			}
+		}
+		if resp == nil || resp.NextPage == 0 {
+			return open, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// proposeSourceBump proposes the update of a single source from a fresh clone of the base branch
+func (r *Releaser) proposeSourceBump(ctx context.Context, b bump) error {
+	dir, err := r.cloneBumpRepo(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.opts.FileSystem.RemoveAll(dir)
+	data, err := r.opts.FileSystem.ReadFile(filepath.Join(dir, bumpPath(r.opts.Config.Bump)))
+	if err != nil {
+		return err
+	}
+	return r.proposeBump(ctx, dir, data, []bump{b})
+}
+
+// proposeBump updates the versions of the configuration in the clone in dir, pushes them to a new branch
+// and opens a pull request with the upstream release notes
+func (r *Releaser) proposeBump(ctx context.Context, dir string, data []byte, bumps []bump) error {
+	cfg := r.opts.Config.Bump
+	versions := map[string]string{}
+	names := make([]string, len(bumps))
+	for i, b := range bumps {
+		versions[b.result.Source] = b.result.To
+		names[i] = b.result.Source
+	}
+	updated, err := SetSourceVersions(data, versions)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(updated, data) {
+		return fmt.Errorf("%s of %s/%s does not contain %s", bumpPath(cfg), cfg.Owner, cfg.Repo, strings.Join(names, ", "))
+	}
+	if err := r.opts.FileSystem.WriteFile(filepath.Join(dir, bumpPath(cfg)), updated, 0644); err != nil {
+		return err
+	}
+
+	branch := fmt.Sprintf("%s%d", bumpBranchPrefix, time.Now().Unix())
+	title := "Bump " + strings.Join(names, ", ")
+	if len(bumps) == 1 {
+		b := bumps[0]
+		branch = bumpBranchPrefix + b.result.Source + "-" + b.result.To
+		title = fmt.Sprintf("Bump %s from %s to %s", b.result.Source, b.result.From, b.result.To)
+	}
+
+	gitCtx, cancel := r.withGitTimeout(ctx)
+	defer cancel()
+	if len(bumps) == 1 {
+		// the branch of a closed pull request may still exist, it is not reused
+		exists, err := r.opts.Git.RemoteBranchExists(gitCtx, dir, branch)
+		if err != nil {
+			return fmt.Errorf("checking branch %s: %w", branch, err)
+		}
+		if exists {
+			r.log.Info("Branch ", branch, " already exists, creating a new one")
+			branch = fmt.Sprintf("%s-%d", branch, time.Now().Unix())
+		}
+	}
+	if err := r.opts.Git.Branch(gitCtx, dir, branch); err != nil {
+		return fmt.Errorf("creating branch %s: %w", branch, err)
+	}
+	if err := r.opts.Git.CommitAndPush(gitCtx, dir, title, bumpPath(cfg)); err != nil {
+		return err
+	}
+
+	pulls := r.host(cfg.Host).pulls
+	pull, _, err := pulls.Create(ctx, cfg.Owner, cfg.Repo, &github.NewPullRequest{
+		Title: github.String(title),
+		Head:  github.String(branch),
+		Base:  github.String(bumpBase(cfg)),
+		Body:  github.String(r.bumpBody(ctx, bumps)),
+	})
+	if err != nil {
+		return fmt.Errorf("opening pull request for branch %s: %w", branch, err)
+	}
+	r.log.Info("Opened pull request ", pull.GetHTMLURL())
+	r.assignPullRequest(ctx, pulls, cfg.Owner, cfg.Repo, pull, cfg.Reviewers, cfg.Labels)
+	for _, b := range bumps {
+		b.result.PullRequestURL = pull.GetHTMLURL()
+	}
+	return nil
+}
+
+// bumpBody lists the version updates with their upstream release notes and marks the bumped sources,
+// so that later runs skip them while the pull request is open
+func (r *Releaser) bumpBody(ctx context.Context, bumps []bump) string {
+	var b strings.Builder
+	for _, bump := range bumps {
+		fmt.Fprintf(&b, "## %s %s → %s\n\n", bump.result.Source, bump.result.From, bump.result.To)
+		rel, err := r.upstreamRelease(ctx, bump.cfg)
+		if err != nil {
+			r.log.Warn("Not adding upstream release notes of ", bump.cfg.Name, ": ", err)
+			fmt.Fprintf(&b, "Upstream repository: %s\n\n", bump.cfg.Repo)
+		} else {
+			fmt.Fprintf(&b, "[Upstream release %s](%s)\n\n%s\n\n", rel.GetTagName(), rel.GetHTMLURL(), strings.TrimSpace(rel.GetBody()))
+		}
+	}
+	for _, bump := range bumps {
+		fmt.Fprintf(&b, "<!-- chart-releaser-bump: %s -->\n", bump.result.Source)
+	}
+	return b.String()
+}
+
+// cloneBumpRepo clones the base branch of the repository holding the configuration into a temporary directory
+func (r *Releaser) cloneBumpRepo(ctx context.Context) (string, error) {
+	cfg := r.opts.Config.Bump
+	dir, err := r.opts.FileSystem.MkdirTemp(r.opts.WorkDir, "bump-")
+	if err != nil {
+		return "", err
+	}
+	r.log.Info("Cloning ", cfg.Owner, "/", cfg.Repo)
+	ctx, cancel := r.withGitTimeout(ctx)
+	defer cancel()
+	err = r.opts.Git.Clone(ctx, r.host(cfg.Host).endpoints.cloneURL(cfg.Owner+"/"+cfg.Repo), dir, bumpBase(cfg))
+	if err != nil {
+		r.opts.FileSystem.RemoveAll(dir)
+		return "", fmt.Errorf("cloning %s/%s: %w", cfg.Owner, cfg.Repo, err)
+	}
+	return dir, nil
+}
+
+func bumpBase(cfg *BumpConfiguration) string {
+	if cfg.Branch == "" {
+		return defaultBumpBase
+	}
+	return cfg.Branch
+}
+
+func bumpPath(cfg *BumpConfiguration) string {
+	if cfg.Path == "" {
+		return defaultBumpPath
+	}
+	return cfg.Path
+}
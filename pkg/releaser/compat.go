@@ -0,0 +1,407 @@
+package releaser
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// CompatAnnotate adds the breaking changes of non-major versions to the chart annotations and release notes
+	CompatAnnotate = "annotate"
+	// CompatBlock fails non-major versions with breaking changes, so that they are not published
+	CompatBlock = "block"
+
+	// breakingChangesAnnotation lists the breaking changes of an annotated chart
+	breakingChangesAnnotation = "gardener-community.github.io/breaking-changes"
+)
+
+// CompatReport classifies the changes of a chart version compared to a previous version
+type CompatReport struct {
+	// From is the version the chart is compared to
+	From string `json:"from"`
+	// Major is true if the major version was bumped, breaking changes are expected then
+	Major bool `json:"major"`
+	// Breaking changes break existing value overrides or remove resources: removed values,
+	// values of a different type, removed resources, and removed CRDs or CRD versions
+	Breaking []CompatChange `json:"breaking,omitempty"`
+	// Additive changes are added values, resources, CRDs and CRD versions, and changed defaults
+	Additive []CompatChange `json:"additive,omitempty"`
+	// Warnings are parts of the check which could not be done, e.g. because a chart did not render
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// CompatChange is a single change of a value ("value"), a rendered resource ("resource") or a CRD ("crd")
+type CompatChange struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Change string `json:"change"`
+}
+
+func (c CompatChange) String() string {
+	return fmt.Sprintf("%s %s %s", c.Kind, c.Name, c.Change)
+}
+
+// BreakingInNonMajor reports whether there are breaking changes although the major version was not bumped
+func (c *CompatReport) BreakingInNonMajor() bool {
+	return !c.Major && len(c.Breaking) > 0
+}
+
+// Markdown renders the breaking changes as list
+func (c *CompatReport) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Breaking changes since %s:\n\n", c.From)
+	if len(c.Breaking) == 0 {
+		b.WriteString("None.\n")
+	}
+	for _, change := range c.Breaking {
+		fmt.Fprintf(&b, "- %s `%s` %s\n", change.Kind, change.Name, change.Change)
+	}
+	return b.String()
+}
+
+func (c *CompatReport) add(breaking bool, kind string, name string, change string) {
+	if breaking {
+		c.Breaking = append(c.Breaking, CompatChange{Kind: kind, Name: name, Change: change})
+	} else {
+		c.Additive = append(c.Additive, CompatChange{Kind: kind, Name: name, Change: change})
+	}
+}
+
+// compatMode returns how breaking changes of a source are handled, the mode of the source takes precedence
+func (r *Releaser) compatMode(cfg SrcConfiguration) string {
+	if cfg.Compat != nil && cfg.Compat.OnBreaking != "" {
+		return cfg.Compat.OnBreaking
+	}
+	if r.opts.Config.Compat != nil {
+		return r.opts.Config.Compat.OnBreaking
+	}
+	return ""
+}
+
+func validateCompat(config Configuration) error {
+	check := func(c *CompatConfiguration) error {
+		if c == nil || c.OnBreaking == "" || c.OnBreaking == CompatAnnotate || c.OnBreaking == CompatBlock {
+			return nil
+		}
+		return fmt.Errorf("unknown compat mode %q, expected %q or %q", c.OnBreaking, CompatAnnotate, CompatBlock)
+	}
+	if err := check(config.Compat); err != nil {
+		return err
+	}
+	for _, cfg := range config.SrcCfg {
+		if err := check(cfg.Compat); err != nil {
+			return fmt.Errorf("source %s: %w", cfg.Name, err)
+		}
+	}
+	return nil
+}
+
+// applyCompat checks a chart for breaking changes and, if the major version was not bumped, blocks it
+// or adds the breaking changes to its annotations depending on the compat mode of the source
+func (r *Releaser) applyCompat(cfg SrcConfiguration, previous *chartValues, current *chartValues, versionReport *VersionReport) error {
+	compat := checkCompat(previous, current)
+	versionReport.Compat = compat
+	for _, w := range compat.Warnings {
+		r.log.Warn("Compat check of ", cfg.Name, " ", cfg.Version, ": ", w)
+	}
+	if !compat.BreakingInNonMajor() {
+		return nil
+	}
+	changes := make([]string, len(compat.Breaking))
+	for i, c := range compat.Breaking {
+		changes[i] = c.String()
+	}
+	switch r.compatMode(cfg) {
+	case CompatBlock:
+		return fmt.Errorf("breaking changes in non-major version bump from %s: %s", compat.From, strings.Join(changes, ", "))
+	case CompatAnnotate:
+		md := current.chart.Metadata
+		if md.Annotations == nil {
+			md.Annotations = map[string]string{}
+		}
+		md.Annotations[breakingChangesAnnotation] = strings.Join(changes, "; ")
+	}
+	r.log.Warn(cfg.Name, " ", cfg.Version, " has breaking changes since ", compat.From, ": ", strings.Join(changes, ", "))
+	return nil
+}
+
+// checkCompat compares the values, the resources rendered with the default values and the CRDs
+// of a chart to a previous version
+func checkCompat(previous *chartValues, current *chartValues) *CompatReport {
+	report := &CompatReport{From: previous.version}
+	from, err := semver.NewVersion(previous.version)
+	if err == nil {
+		to, err := semver.NewVersion(current.version)
+		report.Major = err == nil && to.Major() > from.Major()
+	}
+
+	diff := diffValues(previous, current)
+	for _, c := range diff.Removed {
+		report.add(true, "value", c.Path, "removed")
+	}
+	for _, c := range diff.Changed {
+		// setting a default for null is no type change
+		if c.Old != nil && valueType(c.Old) != valueType(c.New) {
+			report.add(true, "value", c.Path, fmt.Sprintf("changed from %s to %s", valueType(c.Old), valueType(c.New)))
+		} else {
+			report.add(false, "value", c.Path, "default changed")
+		}
+	}
+	for _, c := range diff.Added {
+		report.add(false, "value", c.Path, "added")
+	}
+
+	oldResources, oldErr := renderResources(previous.chart)
+	newResources, newErr := renderResources(current.chart)
+	switch {
+	case oldErr != nil:
+		report.Warnings = append(report.Warnings, fmt.Sprintf("rendering %s: %v", previous.version, oldErr))
+	case newErr != nil:
+		report.Warnings = append(report.Warnings, fmt.Sprintf("rendering %s: %v", current.version, newErr))
+	default:
+		compareSets(report, "resource", resourceNames(oldResources), resourceNames(newResources))
+		oldCRDs := crdVersions(previous.chart, oldResources)
+		newCRDs := crdVersions(current.chart, newResources)
+		for _, name := range sortedKeys(oldCRDs) {
+			versions, ok := newCRDs[name]
+			if !ok {
+				report.add(true, "crd", name, "removed")
+				continue
+			}
+			for _, v := range sortedKeys(oldCRDs[name]) {
+				if !versions[v] {
+					report.add(true, "crd", name, "version "+v+" removed")
+				}
+			}
+			for _, v := range sortedKeys(versions) {
+				if !oldCRDs[name][v] {
+					report.add(false, "crd", name, "version "+v+" added")
+				}
+			}
+		}
+		for _, name := range sortedKeys(newCRDs) {
+			if _, ok := oldCRDs[name]; !ok {
+				report.add(false, "crd", name, "added")
+			}
+		}
+	}
+	return report
+}
+
+func valueType(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "map"
+	case []any:
+		return "list"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// k8sObject are the fields of rendered resources which identify them
+type k8sObject struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		Version  string `json:"version"`
+		Versions []struct {
+			Name string `json:"name"`
+		} `json:"versions"`
+	} `json:"spec"`
+}
+
+// renderResources renders the templates of a chart and its subcharts with the default values.
+// Conditions of subcharts are not evaluated, all subcharts are rendered.
+func renderResources(c *chart.Chart) ([]k8sObject, error) {
+	values, err := chartutil.ToRenderValues(c, map[string]any{},
+		chartutil.ReleaseOptions{Name: c.Name(), Namespace: "default", IsInstall: true}, nil)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := engine.Render(c, values)
+	if err != nil {
+		return nil, err
+	}
+	var objects []k8sObject
+	for _, name := range sortedKeys(rendered) {
+		if filepath.Ext(name) != ".yaml" && filepath.Ext(name) != ".yml" {
+			continue
+		}
+		for _, manifest := range releaseutil.SplitManifests(rendered[name]) {
+			objects = append(objects, parseObject(manifest)...)
+		}
+	}
+	return objects, nil
+}
+
+func parseObject(manifest string) []k8sObject {
+	var o k8sObject
+	if err := yaml.Unmarshal([]byte(manifest), &o); err != nil || o.Kind == "" {
+		return nil
+	}
+	return []k8sObject{o}
+}
+
+func resourceNames(objects []k8sObject) map[string]bool {
+	names := map[string]bool{}
+	for _, o := range objects {
+		names[o.Kind+"/"+o.Metadata.Name] = true
+	}
+	return names
+}
+
+// crdVersions returns the versions of the CRDs in the crds directories and the rendered templates by name
+func crdVersions(c *chart.Chart, rendered []k8sObject) map[string]map[string]bool {
+	objects := rendered
+	for _, crd := range c.CRDObjects() {
+		for _, manifest := range releaseutil.SplitManifests(string(crd.File.Data)) {
+			objects = append(objects, parseObject(manifest)...)
+		}
+	}
+	crds := map[string]map[string]bool{}
+	for _, o := range objects {
+		if o.Kind != "CustomResourceDefinition" {
+			continue
+		}
+		versions := map[string]bool{}
+		if o.Spec.Version != "" {
+			versions[o.Spec.Version] = true
+		}
+		for _, v := range o.Spec.Versions {
+			versions[v.Name] = true
+		}
+		crds[o.Metadata.Name] = versions
+	}
+	return crds
+}
+
+// compareSets adds removed names as breaking and added names as additive changes
+func compareSets(report *CompatReport, kind string, old map[string]bool, current map[string]bool) {
+	for _, name := range sortedKeys(old) {
+		if !current[name] {
+			report.add(true, kind, name, "removed")
+		}
+	}
+	for _, name := range sortedKeys(current) {
+		if !old[name] {
+			report.add(false, kind, name, "added")
+		}
+	}
+}
+
+// CompatResult is the result of checking the configured version of a source against the published versions
+type CompatResult struct {
+	Source  string        `json:"source"`
+	Version string        `json:"version"`
+	Compat  *CompatReport `json:"compat,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// Compat builds the configured version of the given sources, or all sources if names is empty,
+// and checks it for breaking changes against the latest published version which is older.
+// Sources which could not be checked and breaking changes in non-major versions are returned as Errors.
+func (r *Releaser) Compat(ctx context.Context, names []string) ([]*CompatResult, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	destRepo, err := r.opts.FileSystem.MkdirTemp(r.opts.WorkDir, "destrepo-")
+	if err != nil {
+		return nil, err
+	}
+	defer r.opts.FileSystem.RemoveAll(destRepo)
+
+	err = r.cloneDestinationRepo(ctx, destRepo)
+	if err != nil {
+		return nil, fmt.Errorf("cloning destination repository: %w", err)
+	}
+	index, err := r.loadIndex(filepath.Join(destRepo, "index.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("reading index of destination repository: %w", err)
+	}
+
+	results := make([]*CompatResult, len(r.opts.Config.SrcCfg))
+	errs := make([]error, len(r.opts.Config.SrcCfg))
+	r.forEachSource(ctx, func(i int, cfg SrcConfiguration) {
+		if len(names) > 0 && !contains(names, cfg.Name) {
+			return
+		}
+		results[i] = &CompatResult{Source: cfg.Name, Version: cfg.Version}
+		results[i].Compat, errs[i] = r.compatSource(ctx, cfg, index)
+		if errs[i] == nil && results[i].Compat != nil && results[i].Compat.BreakingInNonMajor() {
+			errs[i] = fmt.Errorf("breaking changes in non-major version bump from %s", results[i].Compat.From)
+		}
+		if errs[i] != nil {
+			results[i].Error = errs[i].Error()
+			errs[i] = &SourceError{Source: cfg.Name, Version: cfg.Version, Err: errs[i]}
+		}
+	})
+
+	var checked []*CompatResult
+	for _, result := range results {
+		if result != nil {
+			checked = append(checked, result)
+		}
+	}
+	var sourceErrs Errors
+	for _, err := range errs {
+		if err != nil {
+			sourceErrs = append(sourceErrs, err)
+		}
+	}
+	if ctx.Err() != nil {
+		return checked, fmt.Errorf("compat check interrupted: %w", ctx.Err())
+	}
+	return checked, sourceErrs.ErrorOrNil()
+}
+
+// compatSource builds the configured version of a source and compares it to the previous published version,
+// it returns nil if no older version has been published
+func (r *Releaser) compatSource(ctx context.Context, cfg SrcConfiguration, index *repo.IndexFile) (*CompatReport, error) {
+	version, err := semver.NewVersion(cfg.Version)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := r.publishedValues(ctx, index, cfg.Name, version)
+	if err != nil || previous == nil {
+		return nil, err
+	}
+	c, err := r.getTopLevelChart(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	current, err := newChartValues(&c)
+	if err != nil {
+		return nil, err
+	}
+	return checkCompat(previous, current), nil
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package releaser
+
+import (
+	"fmt"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func TestCheckCompatFilledValues(t *testing.T) {
+	values := func(version string, values map[string]any) *chartValues {
+		v, err := newChartValues(&chart.Chart{
+			Metadata: &chart.Metadata{APIVersion: "v2", Name: "foo", Version: version},
+			Values:   values,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	previous := values("1.0.0", map[string]any{"tolerations": map[string]any{}, "resources": nil, "replicas": nil, "image": map[string]any{"tag": "v1"}})
+	current := values("1.1.0", map[string]any{
+		"tolerations": map[string]any{"key": "foo"},
+		"resources":   map[string]any{"limits": map[string]any{"cpu": "1"}},
+		"replicas":    2,
+		"image":       map[string]any{},
+	})
+
+	report := checkCompat(previous, current)
+	want := []CompatChange{{Kind: "value", Name: "image.tag", Change: "removed"}}
+	if fmt.Sprint(report.Breaking) != fmt.Sprint(want) {
+		t.Errorf("expected only image.tag to be breaking, got %v", report.Breaking)
+	}
+	want = []CompatChange{
+		{Kind: "value", Name: "replicas", Change: "default changed"},
+		{Kind: "value", Name: "image", Change: "added"},
+		{Kind: "value", Name: "resources.limits.cpu", Change: "added"},
+		{Kind: "value", Name: "tolerations.key", Change: "added"},
+	}
+	if fmt.Sprint(report.Additive) != fmt.Sprint(want) {
+		t.Errorf("unexpected additive changes %v", report.Additive)
+	}
+}
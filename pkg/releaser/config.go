@@ -3,11 +3,85 @@ package releaser
 type Configuration struct {
 	SrcCfg []SrcConfiguration `mapstructure:"sources"`
 	DstCfg DstConfiguration   `mapstructure:"destination"`
+	// ReleaseNotesTemplate is the path of a Go template file the notes of the GitHub releases
+	// are rendered from, it is used for all sources without a template of their own
+	ReleaseNotesTemplate string `mapstructure:"releaseNotesTemplate" yaml:"releaseNotesTemplate,omitempty"`
+	// Signing enables provenance files for the released chart packages
+	Signing *SigningConfiguration `mapstructure:"signing" yaml:"signing,omitempty"`
+	// Compat configures how breaking changes in non-major versions are handled
+	Compat *CompatConfiguration `mapstructure:"compat" yaml:"compat,omitempty"`
+	// Bump is the repository holding this configuration, the bump command proposes version updates against it
+	Bump *BumpConfiguration `mapstructure:"bump" yaml:"bump,omitempty"`
+	// Validation configures the schemas the validate command checks rendered manifests against
+	Validation *ValidationConfiguration `mapstructure:"validation" yaml:"validation,omitempty"`
+}
+
+// ValidationConfiguration configures the schema validation of rendered manifests
+type ValidationConfiguration struct {
+	// KubernetesVersions are the Kubernetes versions manifests are validated for, e.g. "1.24.0"
+	KubernetesVersions []string `mapstructure:"kubernetesVersions" yaml:"kubernetesVersions,omitempty"`
+	// SchemaURL serves the Kubernetes schemas as "<version>-standalone-strict/<kind>-<group>-<version>.json",
+	// defaults to DefaultSchemaURL
+	SchemaURL string `mapstructure:"schemaURL" yaml:"schemaURL,omitempty"`
+	// Schemas is a directory with additional schemas of custom resources as "<group>/<kind>_<version>.json",
+	// they take precedence over the bundled schemas
+	Schemas string `mapstructure:"schemas" yaml:"schemas,omitempty"`
+}
+
+// BumpConfiguration configures the pull requests of the bump command
+type BumpConfiguration struct {
+	Owner string `mapstructure:"owner" yaml:"owner,omitempty"`
+	Repo  string `mapstructure:"repo" yaml:"repo,omitempty"`
+	// Host is the GitHub Enterprise Server host of the repository, defaults to github.com
+	Host string `mapstructure:"host" yaml:"host,omitempty"`
+	// Branch is the base branch of the pull requests, defaults to "main"
+	Branch string `mapstructure:"branch" yaml:"branch,omitempty"`
+	// Path is the path of the configuration file in the repository, defaults to "config.yaml"
+	Path string `mapstructure:"path" yaml:"path,omitempty"`
+	// Group proposes all version updates in one pull request instead of one per source
+	Group bool `mapstructure:"group" yaml:"group,omitempty"`
+	// Reviewers are requested to review the pull requests, teams are given as "org/team"
+	Reviewers []string `mapstructure:"reviewers" yaml:"reviewers,omitempty"`
+	Labels    []string `mapstructure:"labels" yaml:"labels,omitempty"`
+}
+
+// CompatConfiguration configures the check for breaking changes against the previous published version
+type CompatConfiguration struct {
+	// OnBreaking is "annotate" or "block", breaking changes are only reported if it is empty
+	OnBreaking string `mapstructure:"onBreaking" yaml:"onBreaking,omitempty"`
+}
+
+// SigningConfiguration selects the PGP key chart packages are signed with
+type SigningConfiguration struct {
+	// Key is the name of the key in the keyring, e.g. the email address of its identity
+	Key string `mapstructure:"key" yaml:"key,omitempty"`
+	// Keyring is the path of the keyring containing the private key
+	Keyring string `mapstructure:"keyring" yaml:"keyring,omitempty"`
+	// PassphraseFile is the path of a file containing the passphrase of the key, if it is encrypted
+	PassphraseFile string `mapstructure:"passphraseFile" yaml:"passphraseFile,omitempty"`
 }
 
 type DstConfiguration struct {
 	Owner string `mapstructure:"owner"`
 	Repo  string `mapstructure:"repo"`
+	// Host is the GitHub Enterprise Server host of the repository, defaults to github.com
+	Host string `mapstructure:"host" yaml:"host,omitempty"`
+	// PullRequest proposes index updates as pull requests against the pages branch
+	// instead of pushing them directly
+	PullRequest *PullRequestConfiguration `mapstructure:"pullRequest" yaml:"pullRequest,omitempty"`
+}
+
+// PullRequestConfiguration configures the pull requests of index updates. Branch and
+// CommitMessage are Go templates rendered with an IndexUpdate.
+type PullRequestConfiguration struct {
+	// Branch is the name of the branch the index update is pushed to,
+	// defaults to "chart-releaser/{{ .Command }}-{{ .Timestamp }}"
+	Branch string `mapstructure:"branch" yaml:"branch,omitempty"`
+	// CommitMessage is the message of the commit, its first line is the title of the pull request
+	CommitMessage string `mapstructure:"commitMessage" yaml:"commitMessage,omitempty"`
+	// Reviewers are requested to review the pull request, teams are given as "org/team"
+	Reviewers []string `mapstructure:"reviewers" yaml:"reviewers,omitempty"`
+	Labels    []string `mapstructure:"labels" yaml:"labels,omitempty"`
 }
 
 type SrcConfiguration struct {
@@ -15,4 +89,37 @@ type SrcConfiguration struct {
 	Version string   `mapstructure:"version"`
 	Repo    string   `mapstructure:"repo"`
 	Charts  []string `mapstructure:"charts"`
+	// Host is the GitHub Enterprise Server host of the repository, defaults to github.com
+	Host string `mapstructure:"host" yaml:"host,omitempty"`
+	// ReleaseNotesTemplate is the path of a Go template file the notes of the GitHub releases of this source are rendered from
+	ReleaseNotesTemplate string `mapstructure:"releaseNotesTemplate" yaml:"releaseNotesTemplate,omitempty"`
+	// Auth configures the credentials for private repositories, sources without
+	// credentials are cloned and downloaded anonymously
+	Auth *AuthConfiguration `mapstructure:"auth" yaml:"auth,omitempty"`
+	// Constraint restricts the versions fetchLatestVersions updates the source to,
+	// e.g. "~1.53" or ">= 1.50, < 2"
+	Constraint string `mapstructure:"constraint" yaml:"constraint,omitempty"`
+	// Pinned keeps fetchLatestVersions from updating the version of the source
+	Pinned bool `mapstructure:"pinned" yaml:"pinned,omitempty"`
+	// Compat overrides the compat configuration for this source
+	Compat *CompatConfiguration `mapstructure:"compat" yaml:"compat,omitempty"`
+}
+
+// AuthConfiguration are the credentials of a source. A token or username and password are used for
+// the GitHub API, raw downloads and https clones, an SSH key switches clones to ssh.
+type AuthConfiguration struct {
+	Token            SecretRef `mapstructure:"token" yaml:"token,omitempty"`
+	Username         string    `mapstructure:"username" yaml:"username,omitempty"`
+	Password         SecretRef `mapstructure:"password" yaml:"password,omitempty"`
+	SSHKey           SecretRef `mapstructure:"sshKey" yaml:"sshKey,omitempty"`
+	SSHKeyPassphrase SecretRef `mapstructure:"sshKeyPassphrase" yaml:"sshKeyPassphrase,omitempty"`
+	// KnownHosts is the path of a known_hosts file, defaults to ~/.ssh/known_hosts
+	KnownHosts string `mapstructure:"knownHosts" yaml:"knownHosts,omitempty"`
+}
+
+// SecretRef refers to a secret stored in an environment variable or a file,
+// so that secrets do not have to be part of the configuration file
+type SecretRef struct {
+	Env  string `mapstructure:"env" yaml:"env,omitempty"`
+	File string `mapstructure:"file" yaml:"file,omitempty"`
 }
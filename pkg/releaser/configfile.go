@@ -0,0 +1,101 @@
+package releaser
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SetSourceVersions replaces the versions of the given sources in a configuration file by name.
+// Only the version scalars are edited in place, comments, the order and the spelling of keys
+// and the formatting of the rest of the file are preserved.
+func SetSourceVersions(data []byte, versions map[string]string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing configuration: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("configuration is empty")
+	}
+	sources := mappingValue(doc.Content[0], "sources")
+	if sources == nil || sources.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("configuration does not contain a list of sources")
+	}
+
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	for _, source := range sources.Content {
+		name := mappingValue(source, "name")
+		version := mappingValue(source, "version")
+		if name == nil || version == nil {
+			continue
+		}
+		newVersion, ok := versions[name.Value]
+		if !ok || newVersion == version.Value {
+			continue
+		}
+		if version.Kind != yaml.ScalarNode || version.Line > len(lines) {
+			return nil, fmt.Errorf("version of source %s is not a scalar", name.Value)
+		}
+
+		// yaml columns are 1-based, the configuration is expected to be ASCII up to the version
+		line := lines[version.Line-1]
+		start := version.Column - 1
+		old, replacement := scalarText(version.Value, version.Style), scalarText(newVersion, version.Style)
+		if start < 0 || !bytes.HasPrefix(line[start:], []byte(old)) {
+			return nil, fmt.Errorf("version of source %s cannot be edited in place", name.Value)
+		}
+		lines[version.Line-1] = append(append(append([]byte{}, line[:start]...), replacement...), line[start+len(old):]...)
+	}
+	return bytes.Join(lines, nil), nil
+}
+
+// SourceVersions returns the versions of the sources in a configuration file by name
+func SourceVersions(data []byte) (map[string]string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing configuration: %w", err)
+	}
+	versions := map[string]string{}
+	if len(doc.Content) == 0 {
+		return versions, nil
+	}
+	sources := mappingValue(doc.Content[0], "sources")
+	if sources == nil {
+		return versions, nil
+	}
+	for _, source := range sources.Content {
+		name := mappingValue(source, "name")
+		version := mappingValue(source, "version")
+		if name != nil && version != nil {
+			versions[name.Value] = version.Value
+		}
+	}
+	return versions, nil
+}
+
+// mappingValue returns the value of a key in a mapping node or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// scalarText returns the text of a scalar in the given style
+func scalarText(value string, style yaml.Style) string {
+	switch {
+	case style&yaml.DoubleQuotedStyle != 0:
+		return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+	case style&yaml.SingleQuotedStyle != 0:
+		return `'` + strings.ReplaceAll(value, `'`, `''`) + `'`
+	default:
+		return value
+	}
+}
@@ -0,0 +1,46 @@
+package releaser
+
+import "testing"
+
+func TestSetSourceVersions(t *testing.T) {
+	config := `# charts released by the community
+targetDir: charts
+sources:
+    - name: gardener-controlplane
+      version: v1.53.0 # updated by fetchLatestVersions
+      repo: gardener/gardener
+    - name: provider-aws
+      version: "v1.38.0"
+      repo: gardener/gardener-extension-provider-aws
+    - name: provider-gcp
+      version: 'v1.25.0'
+      pinned: true
+`
+	want := `# charts released by the community
+targetDir: charts
+sources:
+    - name: gardener-controlplane
+      version: v1.54.1 # updated by fetchLatestVersions
+      repo: gardener/gardener
+    - name: provider-aws
+      version: "v1.39.0"
+      repo: gardener/gardener-extension-provider-aws
+    - name: provider-gcp
+      version: 'v1.25.0'
+      pinned: true
+`
+	got, err := SetSourceVersions([]byte(config), map[string]string{
+		"gardener-controlplane": "v1.54.1",
+		"provider-aws":          "v1.39.0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("unexpected configuration:\n%s", got)
+	}
+
+	if _, err := SetSourceVersions([]byte("targetDir: charts\n"), nil); err == nil {
+		t.Error("expected an error for a configuration without sources")
+	}
+}
@@ -0,0 +1,144 @@
+package releaser
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/google/go-github/v36/github"
+	"golang.org/x/oauth2"
+)
+
+// Credentials authenticate git operations, downloads and API calls against a repository
+type Credentials struct {
+	Token            string
+	Username         string
+	Password         string
+	SSHKey           []byte
+	SSHKeyPassphrase string
+	KnownHosts       string
+}
+
+// IsSet reports whether the secret refers to an environment variable or a file
+func (s SecretRef) IsSet() bool {
+	return s.Env != "" || s.File != ""
+}
+
+// Resolve returns the secret. Trailing whitespace is stripped from secrets read from files.
+func (s SecretRef) Resolve() (string, error) {
+	switch {
+	case s.Env != "":
+		value, ok := os.LookupEnv(s.Env)
+		if !ok || value == "" {
+			return "", fmt.Errorf("environment variable %s is empty", s.Env)
+		}
+		return value, nil
+	case s.File != "":
+		data, err := os.ReadFile(s.File)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n\t "), nil
+	}
+	return "", nil
+}
+
+// Credentials resolves the secrets of the configuration
+func (a *AuthConfiguration) Credentials() (*Credentials, error) {
+	if a == nil {
+		return nil, nil
+	}
+	creds := &Credentials{
+		Username:   a.Username,
+		KnownHosts: a.KnownHosts,
+	}
+	var err error
+	if creds.Token, err = a.Token.Resolve(); err != nil {
+		return nil, fmt.Errorf("resolving token: %w", err)
+	}
+	if creds.Password, err = a.Password.Resolve(); err != nil {
+		return nil, fmt.Errorf("resolving password: %w", err)
+	}
+	key, err := a.SSHKey.Resolve()
+	if err != nil {
+		return nil, fmt.Errorf("resolving ssh key: %w", err)
+	}
+	if key != "" {
+		creds.SSHKey = []byte(key + "\n")
+	}
+	if creds.SSHKeyPassphrase, err = a.SSHKeyPassphrase.Resolve(); err != nil {
+		return nil, fmt.Errorf("resolving ssh key passphrase: %w", err)
+	}
+	if a.Username != "" && !a.Password.IsSet() {
+		return nil, fmt.Errorf("username %s is configured without a password", a.Username)
+	}
+	return creds, nil
+}
+
+// gitAuth returns the auth method for clones and fetches. Clones over ssh use the SSH key,
+// all others the token or username and password.
+func (c *Credentials) gitAuth(ssh bool) (transport.AuthMethod, error) {
+	switch {
+	case c == nil:
+		return nil, nil
+	case ssh:
+		keys, err := gitssh.NewPublicKeys("git", c.SSHKey, c.SSHKeyPassphrase)
+		if err != nil {
+			return nil, fmt.Errorf("parsing ssh key: %w", err)
+		}
+		var files []string
+		if c.KnownHosts != "" {
+			files = append(files, c.KnownHosts)
+		}
+		keys.HostKeyCallback, err = gitssh.NewKnownHostsCallback(files...)
+		if err != nil {
+			return nil, fmt.Errorf("reading known hosts: %w", err)
+		}
+		return keys, nil
+	case c.Token != "":
+		return &githttp.BasicAuth{Username: "x-access-token", Password: c.Token}, nil
+	case c.Username != "":
+		return &githttp.BasicAuth{Username: c.Username, Password: c.Password}, nil
+	}
+	return nil, nil
+}
+
+// authorize adds the token or username and password to a request
+func (c *Credentials) authorize(req *http.Request) {
+	switch {
+	case c == nil:
+	case c.Token != "":
+		req.Header.Set("Authorization", "token "+c.Token)
+	case c.Username != "":
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+}
+
+// hasAPIAuth reports whether the credentials can be used for the GitHub API
+func (c *Credentials) hasAPIAuth() bool {
+	return c != nil && (c.Token != "" || c.Username != "")
+}
+
+// newGitHubClientWithCredentials returns a GitHub client authenticated with the token or username and password
+func newGitHubClientWithCredentials(ctx context.Context, creds *Credentials, endpoints Endpoints) (*github.Client, error) {
+	if creds.Token != "" || creds.Username == "" {
+		return NewGitHubClient(ctx, creds.Token, endpoints)
+	}
+	api, uploads, err := endpoints.withDefaults().parse()
+	if err != nil {
+		return nil, err
+	}
+	tp := &github.BasicAuthTransport{Username: creds.Username, Password: creds.Password}
+	if httpClient, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok {
+		tp.Transport = httpClient.Transport
+	}
+	client := github.NewClient(tp.Client())
+	client.BaseURL = api
+	client.UploadURL = uploads
+	return client, nil
+}
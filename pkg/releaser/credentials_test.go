@@ -0,0 +1,36 @@
+package releaser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCredentials(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(passwordFile, []byte("s3cr3t\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_TOKEN", "t0ken")
+
+	creds, err := (&AuthConfiguration{
+		Token:    SecretRef{Env: "TEST_TOKEN"},
+		Username: "bot",
+		Password: SecretRef{File: passwordFile},
+	}).Credentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.Token != "t0ken" || creds.Password != "s3cr3t" {
+		t.Errorf("unexpected credentials %+v", creds)
+	}
+
+	_, err = (&AuthConfiguration{Token: SecretRef{Env: "TEST_UNSET_TOKEN"}}).Credentials()
+	if err == nil {
+		t.Error("expected an error for an unset environment variable")
+	}
+	_, err = (&AuthConfiguration{Username: "bot"}).Credentials()
+	if err == nil {
+		t.Error("expected an error for a username without password")
+	}
+}
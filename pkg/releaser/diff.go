@@ -0,0 +1,137 @@
+package releaser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// DiffOptions selects the versions of a source Diff compares and the values they are rendered with
+type DiffOptions struct {
+	Source string
+	From   string
+	To     string
+	// ValuesFiles are merged into the default values of both versions, later files take precedence
+	ValuesFiles []string
+	// Namespace is the namespace of the release, defaults to "default"
+	Namespace string
+}
+
+// ChartDiff lists the resources which differ between two versions of a chart
+type ChartDiff struct {
+	Source    string         `json:"source"`
+	From      string         `json:"from"`
+	To        string         `json:"to"`
+	Resources []ResourceDiff `json:"resources,omitempty"`
+}
+
+// ResourceDiff is a resource which was added, removed or changed, Diff is the unified diff of its manifest
+type ResourceDiff struct {
+	Resource string `json:"resource"`
+	Change   string `json:"change"`
+	Diff     string `json:"diff"`
+}
+
+// Unified returns the unified diffs of all resources
+func (d *ChartDiff) Unified() string {
+	var b strings.Builder
+	for _, r := range d.Resources {
+		b.WriteString(r.Diff)
+	}
+	return b.String()
+}
+
+// Diff builds two versions of a source, renders both with the same values and compares their manifests by resource
+func (r *Releaser) Diff(ctx context.Context, opts DiffOptions) (*ChartDiff, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	if opts.Namespace == "" {
+		opts.Namespace = "default"
+	}
+
+	var cfg *SrcConfiguration
+	for i := range r.opts.Config.SrcCfg {
+		if r.opts.Config.SrcCfg[i].Name == opts.Source {
+			cfg = &r.opts.Config.SrcCfg[i]
+		}
+	}
+	if cfg == nil {
+		return nil, fmt.Errorf("source %s is not configured", opts.Source)
+	}
+	values, err := r.loadValuesFiles(opts.ValuesFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := r.renderVersion(ctx, *cfg, opts.From, values, opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	to, err := r.renderVersion(ctx, *cfg, opts.To, values, opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &ChartDiff{Source: opts.Source, From: opts.From, To: opts.To}
+	for _, resource := range sortedKeys(mergeKeys(from, to)) {
+		before, after := from[resource], to[resource]
+		if before == after {
+			continue
+		}
+		change := "changed"
+		switch {
+		case before == "":
+			change = "added"
+		case after == "":
+			change = "removed"
+		}
+		unified, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(before),
+			B:        difflib.SplitLines(after),
+			FromFile: opts.From + "/" + resource,
+			ToFile:   opts.To + "/" + resource,
+			Context:  3,
+		})
+		if err != nil {
+			return nil, err
+		}
+		diff.Resources = append(diff.Resources, ResourceDiff{Resource: resource, Change: change, Diff: unified})
+	}
+	return diff, nil
+}
+
+// renderVersion builds a version of a source and returns its manifests by resource
+func (r *Releaser) renderVersion(ctx context.Context, cfg SrcConfiguration, version string, values map[string]any, namespace string) (map[string]string, error) {
+	cfg.Version = version
+	c, err := r.getTopLevelChart(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("building %s %s: %w", cfg.Name, version, err)
+	}
+	// rendering modifies the values, every version gets a copy of its own
+	var versionValues map[string]any
+	if err := normalizeValues(values, &versionValues); err != nil {
+		return nil, err
+	}
+	manifests, err := renderManifests(&c, versionValues, namespace, true)
+	if err != nil {
+		return nil, fmt.Errorf("rendering %s %s: %w", cfg.Name, version, err)
+	}
+	resources := map[string]string{}
+	for _, m := range manifests {
+		resources[m.resource] += m.content + "\n"
+	}
+	return resources, nil
+}
+
+func mergeKeys(a map[string]string, b map[string]string) map[string]string {
+	keys := map[string]string{}
+	for k := range a {
+		keys[k] = ""
+	}
+	for k := range b {
+		keys[k] = ""
+	}
+	return keys
+}
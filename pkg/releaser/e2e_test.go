@@ -0,0 +1,1223 @@
+package releaser
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/openpgp" //nolint
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/provenance"
+	"helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	gardenerRepo    = "gardener/gardener"
+	extensionRepo   = "gardener/gardener-extension-provider-foo"
+	destinationRepo = "community/charts"
+)
+
+// testEnv is a hermetic environment with a fake GitHub and local git repositories containing
+// the gardener controlplane chart, an extension with a controller registration and a destination
+// repository which already contains the release 1.0.0 of the controlplane chart
+type testEnv struct {
+	t       *testing.T
+	gh      *fakeGitHub
+	git     *gitFixture
+	commits map[string]string
+	config  Configuration
+}
+
+func controlplaneChart(appVersion string) map[string]string {
+	return map[string]string{
+		"charts/gardener/controlplane/Chart.yaml": `apiVersion: v2
+name: controlplane
+version: 0.1.0
+appVersion: ` + appVersion + `
+`,
+		"charts/gardener/controlplane/values.yaml": `global:
+  image:
+    repository: eu.gcr.io/gardener-project/gardener/apiserver
+    tag: latest
+`,
+		"charts/gardener/controlplane/templates/deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: gardener-apiserver
+spec:
+  selector:
+    matchLabels:
+      app: gardener-apiserver
+  template:
+    metadata:
+      labels:
+        app: gardener-apiserver
+    spec:
+      containers:
+      - image: {{ .Values.global.image.repository }}:{{ .Values.global.image.tag }}
+`,
+	}
+}
+
+const controllerRegistration = `apiVersion: core.gardener.cloud/v1beta1
+kind: ControllerDeployment
+metadata:
+  name: provider-foo
+type: helm
+providerConfig:
+  chart: H4sIAAAAAAAAA==
+  values:
+---
+apiVersion: core.gardener.cloud/v1beta1
+kind: ControllerRegistration
+metadata:
+  name: provider-foo
+spec:
+  resources:
+  - kind: Infrastructure
+    type: foo
+`
+
+func newTestEnv(t *testing.T) *testEnv {
+	env := &testEnv{
+		t:   t,
+		gh:  newFakeGitHub(t),
+		git: newGitFixture(t),
+	}
+
+	env.commits = env.git.addRepo(gardenerRepo, "master", []fixtureVersion{
+		{tag: "v1.0.0", files: controlplaneChart("1.0.0")},
+		{tag: "v1.1.0", files: controlplaneChart("1.1.0")},
+	})
+	for _, tag := range []string{"v1.0.0", "v1.1.0"} {
+		env.gh.addRelease(gardenerRepo, tag, "Gardener "+tag)
+		env.gh.commits[gardenerRepo+"@"+tag] = env.commits[tag]
+	}
+
+	env.gh.addRelease(extensionRepo, "v0.1.0", "Provider foo v0.1.0")
+	env.gh.commits[extensionRepo+"@v0.1.0"] = "0123456789abcdef0123456789abcdef01234567"
+	env.gh.raw[extensionRepo+"/v0.1.0/example/controller-registration.yaml"] = []byte(controllerRegistration)
+
+	// the destination already contains the first release of the controlplane chart
+	published := env.publishedPackage("gardener-controlplane", "1.0.0")
+	env.gh.addRelease(destinationRepo, "gardener-controlplane-1.0.0", "")
+	env.gh.addAsset(destinationRepo, "gardener-controlplane-1.0.0", "gardener-controlplane-1.0.0.tgz", published)
+	index := repo.NewIndexFile()
+	digest, err := provenance.Digest(bytes.NewReader(published))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addToIndex(index, &chart.Metadata{APIVersion: "v2", Name: "gardener-controlplane", Version: "1.0.0"},
+		env.gh.server.URL+"/download/"+destinationRepo+"/releases/download/gardener-controlplane-1.0.0/gardener-controlplane-1.0.0.tgz",
+		digest, index.Generated)
+	indexData, err := yaml.Marshal(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.git.addRepo(destinationRepo, pagesBranch, []fixtureVersion{{files: map[string]string{"index.yaml": string(indexData)}}})
+
+	env.config = Configuration{
+		DstCfg: DstConfiguration{Owner: "community", Repo: "charts"},
+		SrcCfg: []SrcConfiguration{
+			{
+				Name:    "gardener-controlplane",
+				Version: "v1.1.0",
+				Repo:    gardenerRepo,
+				Charts:  []string{"charts/gardener/controlplane"},
+			},
+			{
+				Name:    "provider-foo",
+				Version: "v0.1.0",
+				Repo:    extensionRepo,
+				Charts:  []string{"controller-registration"},
+			},
+		},
+	}
+	return env
+}
+
+// publishedPackage returns a minimal chart package as it would have been released before
+func (env *testEnv) publishedPackage(name string, version string) []byte {
+	dir := env.t.TempDir()
+	path, err := chartutil.Save(&chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: "v2", Name: name, Version: version},
+	}, dir)
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	return data
+}
+
+func (env *testEnv) releaser(targetDir string) *Releaser {
+	r, err := New(Options{
+		Config:    env.config,
+		TargetDir: targetDir,
+		CacheDir:  env.t.TempDir(),
+		WorkDir:   env.t.TempDir(),
+		Token:     "test-token",
+		Endpoints: env.gh.endpoints(env.git.root),
+		Logger:    testLogger(env.t),
+	})
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	return r
+}
+
+func TestUpdate(t *testing.T) {
+	env := newTestEnv(t)
+	targetDir := t.TempDir()
+
+	report, err := env.releaser(targetDir).Update(context.Background())
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	if len(report.Sources) != 2 {
+		t.Fatalf("expected 2 sources in report, got %d", len(report.Sources))
+	}
+	cp := report.Sources[0]
+	if len(cp.Versions) != 1 || cp.Versions[0].Version != "v1.1.0" {
+		t.Fatalf("expected only v1.1.0 of the controlplane chart to be released, got %+v", cp.Versions)
+	}
+	for _, s := range report.Sources {
+		for _, v := range s.Versions {
+			if v.Outcome != OutcomeReleased {
+				t.Errorf("%s %s: expected outcome %s, got %s (%s)", s.Name, v.Version, OutcomeReleased, v.Outcome, v.Error)
+			}
+			if v.Digest == "" || v.ReleaseURL == "" {
+				t.Errorf("%s %s: digest or release URL missing in report", s.Name, v.Version)
+			}
+		}
+	}
+	if got := cp.Versions[0].Commit; got != env.commits["v1.1.0"] {
+		t.Errorf("expected commit %s in report, got %s", env.commits["v1.1.0"], got)
+	}
+
+	// the controlplane chart is imported from the tagged commit
+	data := env.gh.asset(destinationRepo, "gardener-controlplane-1.1.0", "gardener-controlplane-1.1.0.tgz")
+	if data == nil {
+		t.Fatal("release asset gardener-controlplane-1.1.0.tgz was not uploaded")
+	}
+	c, err := loader.LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Metadata.AppVersion != "1.1.0" {
+		t.Errorf("expected appVersion 1.1.0, got %s", c.Metadata.AppVersion)
+	}
+	if !strings.Contains(string(valuesFile(c)), "tag: v1.1.0") {
+		t.Errorf("expected latest image tag to be replaced by the version, got\n%s", valuesFile(c))
+	}
+	if rel := env.gh.release(destinationRepo, "gardener-controlplane-1.1.0"); !strings.HasPrefix(rel.GetBody(), "Gardener v1.1.0\n") {
+		t.Errorf("expected upstream release notes, got %q", rel.GetBody())
+	}
+
+	// the extension chart contains the generated controller chart
+	data = env.gh.asset(destinationRepo, "provider-foo-0.1.0", "provider-foo-0.1.0.tgz")
+	if data == nil {
+		t.Fatal("release asset provider-foo-0.1.0.tgz was not uploaded")
+	}
+	c, err = loader.LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Dependencies()) != 1 || c.Dependencies()[0].Name() != "controller" {
+		t.Fatalf("expected controller subchart, got %v", c.Dependencies())
+	}
+	tpl := string(c.Dependencies()[0].Templates[0].Data)
+	if !strings.Contains(tpl, "kind: ControllerRegistration") || !strings.Contains(tpl, "toYaml .Values.values") {
+		t.Errorf("unexpected controller registration template:\n%s", tpl)
+	}
+
+	// the index lists the old and both new releases with the digests of the uploaded packages
+	index := env.git.index(destinationRepo)
+	for name, version := range map[string]string{
+		"gardener-controlplane": "1.0.0",
+		"provider-foo":          "0.1.0",
+	} {
+		if !index.Has(name, version) {
+			t.Errorf("index does not contain %s %s", name, version)
+		}
+	}
+	cv, err := index.Get("gardener-controlplane", "1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cv.Digest != cp.Versions[0].Digest {
+		t.Errorf("expected digest %s in index, got %s", cp.Versions[0].Digest, cv.Digest)
+	}
+
+	// a second run has nothing to do
+	report, err = env.releaser(t.TempDir()).Update(context.Background())
+	if err != nil {
+		t.Fatalf("second Update failed: %v", err)
+	}
+	for _, s := range report.Sources {
+		if len(s.Versions) != 0 {
+			t.Errorf("%s: expected no versions in second run, got %d", s.Name, len(s.Versions))
+		}
+	}
+}
+
+func TestExportSharedRepository(t *testing.T) {
+	env := newTestEnv(t)
+	// sources sharing a repository use the same clone, but check out different tags
+	env.config.SrcCfg = nil
+	for i := 0; i < 6; i++ {
+		env.config.SrcCfg = append(env.config.SrcCfg, SrcConfiguration{
+			Name:    fmt.Sprintf("controlplane-%d", i),
+			Version: []string{"v1.0.0", "v1.1.0"}[i%2],
+			Repo:    gardenerRepo,
+			Charts:  []string{"charts/gardener/controlplane"},
+		})
+	}
+	targetDir := t.TempDir()
+
+	report, err := env.releaser(targetDir).Export(context.Background())
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	for i, s := range report.Sources {
+		if s.Name != env.config.SrcCfg[i].Name {
+			t.Errorf("expected source %s at position %d of the report, got %s", env.config.SrcCfg[i].Name, i, s.Name)
+		}
+		if s.Versions[0].Commit != env.commits[env.config.SrcCfg[i].Version] {
+			t.Errorf("%s: unexpected commit %s", s.Name, s.Versions[0].Commit)
+		}
+		c, err := loader.Load(filepath.Join(targetDir, s.Name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the app version is part of the checked out files
+		if want := strings.TrimPrefix(env.config.SrcCfg[i].Version, "v"); c.Metadata.AppVersion != want {
+			t.Errorf("%s: expected app version %s, got %s", s.Name, want, c.Metadata.AppVersion)
+		}
+	}
+}
+
+func TestUpdatePullRequest(t *testing.T) {
+	env := newTestEnv(t)
+	env.config.DstCfg.PullRequest = &PullRequestConfiguration{
+		Branch:        "index/{{ .Command }}-{{ len .Charts }}",
+		CommitMessage: "Release {{ len .Charts }} charts\n\n{{ range .Charts }}- {{ . }}\n{{ end }}",
+		Reviewers:     []string{"alice", "gardener/charts"},
+		Labels:        []string{"release"},
+	}
+
+	report, err := env.releaser(t.TempDir()).Update(context.Background())
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	pulls := env.gh.pulls[destinationRepo]
+	if len(pulls) != 1 {
+		t.Fatalf("expected one pull request, got %d", len(pulls))
+	}
+	pull := pulls[0]
+	if report.PullRequestURL != pull.GetHTMLURL() {
+		t.Errorf("expected pull request %s in report, got %q", pull.GetHTMLURL(), report.PullRequestURL)
+	}
+	if pull.GetTitle() != "Release 2 charts" || !strings.Contains(pull.GetBody(), "- provider-foo-0.1.0") {
+		t.Errorf("unexpected pull request %q: %q", pull.GetTitle(), pull.GetBody())
+	}
+	if pull.GetHead().GetRef() != "index/update-2" || pull.GetBase().GetRef() != pagesBranch {
+		t.Errorf("unexpected branches %s -> %s", pull.GetHead().GetRef(), pull.GetBase().GetRef())
+	}
+	if len(pull.RequestedReviewers) != 1 || len(pull.RequestedTeams) != 1 || len(pull.Labels) != 1 {
+		t.Errorf("reviewers, teams or labels missing: %+v", pull)
+	}
+
+	// the pages branch is only changed by merging the pull request
+	if env.git.index(destinationRepo).Has("provider-foo", "0.1.0") {
+		t.Error("index on the pages branch was changed")
+	}
+	if !env.git.indexOn(destinationRepo, "index/update-2").Has("provider-foo", "0.1.0") {
+		t.Error("index on the pull request branch does not contain provider-foo 0.1.0")
+	}
+
+	// the versions of the open pull request are not proposed again
+	report, err = env.releaser(t.TempDir()).Update(context.Background())
+	if err != nil {
+		t.Fatalf("second Update failed: %v", err)
+	}
+	if len(env.gh.pulls[destinationRepo]) != 1 || report.PullRequestURL != "" {
+		t.Errorf("expected no further pull request, got %d", len(env.gh.pulls[destinationRepo]))
+	}
+}
+
+func TestUpdatePullRequestShortTag(t *testing.T) {
+	env := newTestEnv(t)
+	env.config.DstCfg.PullRequest = &PullRequestConfiguration{}
+	// tags which are no canonical semver are released as chart version without the v prefix
+	env.gh.releases[extensionRepo] = nil
+	env.gh.addRelease(extensionRepo, "v0.2", "Provider foo v0.2")
+	env.gh.commits[extensionRepo+"@v0.2"] = "0123456789abcdef0123456789abcdef01234567"
+	env.gh.raw[extensionRepo+"/v0.2/example/controller-registration.yaml"] = []byte(controllerRegistration)
+
+	if _, err := env.releaser(t.TempDir()).Update(context.Background()); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	pulls := env.gh.pulls[destinationRepo]
+	if len(pulls) != 1 || !strings.Contains(pulls[0].GetBody(), "<!-- chart-releaser-index: provider-foo-0.2 -->") {
+		t.Fatalf("expected one pull request adding provider-foo-0.2, got %v", pulls)
+	}
+
+	report, err := env.releaser(t.TempDir()).Update(context.Background())
+	if err != nil {
+		t.Fatalf("second Update failed: %v", err)
+	}
+	if len(env.gh.pulls[destinationRepo]) != 1 || len(report.Sources[1].Versions) != 0 {
+		t.Errorf("expected provider-foo v0.2 not to be proposed again, got %d pull requests", len(env.gh.pulls[destinationRepo]))
+	}
+}
+
+func TestUpdateReleaseNotes(t *testing.T) {
+	env := newTestEnv(t)
+	notes := filepath.Join(t.TempDir(), "notes.md")
+	err := os.WriteFile(notes, []byte(`{{ .Name }} {{ .Version }} from {{ .Repo }}@{{ .Tag }} ({{ .Commit | trunc 7 }})
+digest: {{ .Digest }}
+{{ range .Subcharts }}subchart: {{ .Name }} {{ .Version }}
+{{ end }}{{ range .Images }}image: {{ . }}
+{{ end }}upstream: {{ .UpstreamNotes }}
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.config.ReleaseNotesTemplate = notes
+	// the images of extensions are defined by the chart embedded in their ControllerDeployment
+	env.gh.raw[extensionRepo+"/v0.1.0/example/controller-registration.yaml"] = []byte(strings.Replace(controllerRegistration,
+		"H4sIAAAAAAAAA==", base64.StdEncoding.EncodeToString(env.extensionControllerChart()), 1))
+
+	report, err := env.releaser(t.TempDir()).Update(context.Background())
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	body := env.gh.release(destinationRepo, "gardener-controlplane-1.1.0").GetBody()
+	for _, want := range []string{
+		"gardener-controlplane 1.1.0 from gardener/gardener@v1.1.0 (" + env.commits["v1.1.0"][:7] + ")",
+		"digest: " + report.Sources[0].Versions[0].Digest,
+		"image: eu.gcr.io/gardener-project/gardener/apiserver:v1.1.0",
+		"upstream: Gardener v1.1.0",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("release notes of gardener-controlplane do not contain %q:\n%s", want, body)
+		}
+	}
+	body = env.gh.release(destinationRepo, "provider-foo-0.1.0").GetBody()
+	if !strings.Contains(body, "subchart: controller 0.1.0") {
+		t.Errorf("release notes of provider-foo do not list the controller subchart:\n%s", body)
+	}
+	if !strings.Contains(body, "image: eu.gcr.io/gardener-project/gardener/extensions/provider-foo:v0.1.0") {
+		t.Errorf("release notes of provider-foo do not list the image of the controller:\n%s", body)
+	}
+}
+
+// extensionControllerChart returns the chart package of an extension controller as embedded in its ControllerDeployment
+func (env *testEnv) extensionControllerChart() []byte {
+	dir := env.t.TempDir()
+	path, err := chartutil.Save(&chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: "v2", Name: "gardener-extension-provider-foo", Version: "0.1.0"},
+		Values: map[string]any{"image": map[string]any{
+			"repository": "eu.gcr.io/gardener-project/gardener/extensions/provider-foo",
+			"tag":        "v0.1.0",
+		}},
+		Raw: []*chart.File{{Name: chartutil.ValuesfileName, Data: []byte(`image:
+  repository: eu.gcr.io/gardener-project/gardener/extensions/provider-foo
+  tag: v0.1.0
+`)}},
+	}, dir)
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	return data
+}
+
+func TestUpdateValuesDiff(t *testing.T) {
+	env := newTestEnv(t)
+	// the published package is downloaded with the token of the private destination repository
+	env.gh.private[destinationRepo] = "test-token"
+
+	report, err := env.releaser(t.TempDir()).Update(context.Background())
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	// the published 1.0.0 has no values, so all values of 1.1.0 are added
+	diff := report.Sources[0].Versions[0].ValuesDiff
+	if diff == nil || diff.From != "1.0.0" {
+		t.Fatalf("expected values diff against 1.0.0, got %+v", diff)
+	}
+	want := []ValueChange{
+		{Path: "global.image.repository", New: "eu.gcr.io/gardener-project/gardener/apiserver"},
+		{Path: "global.image.tag", New: "v1.1.0"},
+	}
+	if fmt.Sprint(diff.Added) != fmt.Sprint(want) || len(diff.Removed) != 0 || len(diff.Changed) != 0 {
+		t.Errorf("unexpected values diff: %+v", diff)
+	}
+	if report.Sources[1].Versions[0].ValuesDiff != nil {
+		t.Error("expected no values diff for the first release of provider-foo")
+	}
+
+	body := env.gh.release(destinationRepo, "gardener-controlplane-1.1.0").GetBody()
+	if !strings.Contains(body, "- added `global.image.tag`: `\"v1.1.0\"`") {
+		t.Errorf("release notes do not contain the values diff:\n%s", body)
+	}
+	if md := report.Markdown(); !strings.Contains(md, "## gardener-controlplane v1.1.0") {
+		t.Errorf("report does not contain the values diff:\n%s", md)
+	}
+}
+
+// breakingPackage returns a controlplane package 1.0.0 with a value, a resource and a CRD
+// which do not exist in 1.1.0 anymore
+func (env *testEnv) breakingPackage() []byte {
+	dir := env.t.TempDir()
+	path, err := chartutil.Save(&chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: "v2", Name: "gardener-controlplane", Version: "1.0.0"},
+		Values:   map[string]any{"replicas": 1},
+		Raw:      []*chart.File{{Name: chartutil.ValuesfileName, Data: []byte("replicas: 1\n")}},
+		Templates: []*chart.File{{Name: "templates/config.yaml", Data: []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: gardener-config
+`)}},
+		Files: []*chart.File{{Name: "crds/shoots.yaml", Data: []byte(`apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: shoots.core.gardener.cloud
+spec:
+  versions:
+  - name: v1beta1
+`)}},
+	}, dir)
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	return data
+}
+
+func TestUpdateExistingRelease(t *testing.T) {
+	env := newTestEnv(t)
+	// the destination repository is private, its assets are only downloaded with the token
+	env.gh.private[destinationRepo] = "test-token"
+	// a previous run created the release of the controlplane chart, but failed to update the index
+	existing := env.publishedPackage("gardener-controlplane", "1.1.0")
+	env.gh.addRelease(destinationRepo, "gardener-controlplane-1.1.0", "")
+	env.gh.addAsset(destinationRepo, "gardener-controlplane-1.1.0", "gardener-controlplane-1.1.0.tgz", existing)
+
+	report, err := env.releaser(t.TempDir()).Update(context.Background())
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	digest, err := provenance.Digest(bytes.NewReader(existing))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Sources[0].Versions[0].Digest == digest {
+		t.Fatal("expected the rebuilt package to differ from the existing asset")
+	}
+	entry, err := env.git.index(destinationRepo).Get("gardener-controlplane", "1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Digest != digest {
+		t.Errorf("expected the index to contain the digest %s of the existing asset, got %s", digest, entry.Digest)
+	}
+	if !bytes.Equal(env.gh.asset(destinationRepo, "gardener-controlplane-1.1.0", "gardener-controlplane-1.1.0.tgz"), existing) {
+		t.Error("existing asset was replaced")
+	}
+}
+
+func TestUpdateExistingReleaseWithoutAsset(t *testing.T) {
+	env := newTestEnv(t)
+	// a previous run created the release, but failed to upload the package
+	env.gh.addRelease(destinationRepo, "gardener-controlplane-1.1.0", "")
+
+	report, err := env.releaser(t.TempDir()).Update(context.Background())
+	if _, ok := err.(Errors); err == nil || ok {
+		t.Fatalf("expected the incomplete release to abort the run, got %v", err)
+	}
+	if !strings.Contains(report.Error, "has no asset gardener-controlplane-1.1.0.tgz") {
+		t.Errorf("expected the missing asset in the report, got %q", report.Error)
+	}
+	if len(env.gh.release(destinationRepo, "gardener-controlplane-1.1.0").Assets) != 0 {
+		t.Error("the package was uploaded to the existing release")
+	}
+	if env.git.index(destinationRepo).Has("gardener-controlplane", "1.1.0") {
+		t.Error("index contains a release without package")
+	}
+}
+
+func TestUpdateCompat(t *testing.T) {
+	for _, mode := range []string{CompatAnnotate, CompatBlock} {
+		t.Run(mode, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.gh.removeAssets(destinationRepo, "gardener-controlplane-1.0.0")
+			env.gh.addAsset(destinationRepo, "gardener-controlplane-1.0.0", "gardener-controlplane-1.0.0.tgz", env.breakingPackage())
+			env.config.Compat = &CompatConfiguration{OnBreaking: mode}
+
+			report, err := env.releaser(t.TempDir()).Update(context.Background())
+			compat := report.Sources[0].Versions[0].Compat
+			if compat == nil || compat.Major {
+				t.Fatalf("expected compat report of a minor version, got %+v", compat)
+			}
+			want := []CompatChange{
+				{Kind: "value", Name: "replicas", Change: "removed"},
+				{Kind: "resource", Name: "ConfigMap/gardener-config", Change: "removed"},
+				{Kind: "crd", Name: "shoots.core.gardener.cloud", Change: "removed"},
+			}
+			if fmt.Sprint(compat.Breaking) != fmt.Sprint(want) {
+				t.Errorf("unexpected breaking changes: %v", compat.Breaking)
+			}
+
+			if mode == CompatBlock {
+				if errs, ok := err.(Errors); !ok || len(errs) != 1 {
+					t.Fatalf("expected the controlplane chart to be blocked, got %v", err)
+				}
+				if env.git.index(destinationRepo).Has("gardener-controlplane", "1.1.0") {
+					t.Error("blocked chart gardener-controlplane 1.1.0 was published")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Update failed: %v", err)
+			}
+			data := env.gh.asset(destinationRepo, "gardener-controlplane-1.1.0", "gardener-controlplane-1.1.0.tgz")
+			c, err := loader.LoadArchive(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(c.Metadata.Annotations[breakingChangesAnnotation], "value replicas removed") {
+				t.Errorf("chart is not annotated with the breaking changes: %v", c.Metadata.Annotations)
+			}
+			if body := env.gh.release(destinationRepo, "gardener-controlplane-1.1.0").GetBody(); !strings.Contains(body, "## Breaking changes") {
+				t.Errorf("release notes do not list the breaking changes:\n%s", body)
+			}
+		})
+	}
+}
+
+func TestLatestVersion(t *testing.T) {
+	env := newTestEnv(t)
+	r := env.releaser(t.TempDir())
+	cfg := env.config.SrcCfg[0]
+
+	for constraint, want := range map[string]string{"": "v1.1.0", "~1.0": "v1.0.0"} {
+		cfg.Constraint = constraint
+		got, err := r.LatestVersion(context.Background(), cfg)
+		if err != nil {
+			t.Fatalf("constraint %q: %v", constraint, err)
+		}
+		if got != want {
+			t.Errorf("constraint %q: expected %s, got %s", constraint, want, got)
+		}
+	}
+
+	cfg.Constraint = ">= 2"
+	if _, err := r.LatestVersion(context.Background(), cfg); err == nil {
+		t.Error("expected an error if no release satisfies the constraint")
+	}
+}
+
+func TestStatus(t *testing.T) {
+	env := newTestEnv(t)
+	env.config.SrcCfg[0].Version = "v1.0.0"
+
+	statuses, err := env.releaser(t.TempDir()).Status(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	want := []SourceStatus{
+		{Source: "gardener-controlplane", Configured: "v1.0.0", LatestUpstream: "v1.1.0", LatestPublished: "1.0.0", Behind: 1},
+		{Source: "provider-foo", Configured: "v0.1.0", LatestUpstream: "v0.1.0"},
+	}
+	if len(statuses) != len(want) {
+		t.Fatalf("expected %d statuses, got %d", len(want), len(statuses))
+	}
+	for i, s := range statuses {
+		if *s != want[i] {
+			t.Errorf("expected status %+v, got %+v", want[i], *s)
+		}
+		if !s.Outdated() {
+			t.Errorf("%s: expected to be outdated", s.Source)
+		}
+	}
+
+	statuses, err = env.releaser(t.TempDir()).Status(context.Background(), []string{"provider-foo"})
+	if err != nil || len(statuses) != 1 || statuses[0].Source != "provider-foo" {
+		t.Fatalf("expected only the status of provider-foo, got %v %v", statuses, err)
+	}
+}
+
+func TestBump(t *testing.T) {
+	env := newTestEnv(t)
+	env.git.addRepo("community/config", "main", []fixtureVersion{{files: map[string]string{"config.yaml": `sources:
+    - name: gardener-controlplane
+      version: v1.0.0 # bumped by pull requests
+      repo: gardener/gardener
+    - name: provider-foo
+      version: v0.1.0
+      repo: gardener/gardener-extension-provider-foo
+`}}})
+	env.config.Bump = &BumpConfiguration{Owner: "community", Repo: "config", Labels: []string{"dependencies"}}
+	r := env.releaser(t.TempDir())
+
+	results, err := r.Bump(context.Background(), BumpOptions{})
+	if err != nil {
+		t.Fatalf("Bump failed: %v", err)
+	}
+	if len(results) != 2 || results[0].To != "v1.1.0" || results[0].PullRequestURL == "" || results[1].Skipped != "up to date" {
+		t.Fatalf("unexpected results: %+v %+v", results[0], results[1])
+	}
+
+	pull := env.gh.pull("community/config", "1")
+	if pull.GetTitle() != "Bump gardener-controlplane from v1.0.0 to v1.1.0" || pull.GetBase().GetRef() != "main" {
+		t.Errorf("unexpected pull request %q against %s", pull.GetTitle(), pull.GetBase().GetRef())
+	}
+	if !strings.Contains(pull.GetBody(), "Gardener v1.1.0") {
+		t.Errorf("pull request does not contain the upstream release notes:\n%s", pull.GetBody())
+	}
+	if len(pull.Labels) != 1 {
+		t.Errorf("expected the pull request to be labeled, got %v", pull.Labels)
+	}
+	config := string(env.git.file("community/config", pull.GetHead().GetRef(), "config.yaml"))
+	if !strings.Contains(config, "version: v1.1.0 # bumped by pull requests") {
+		t.Errorf("version was not updated in place:\n%s", config)
+	}
+
+	// the open pull request is not opened again
+	results, err = r.Bump(context.Background(), BumpOptions{})
+	if err != nil {
+		t.Fatalf("Bump failed: %v", err)
+	}
+	if !strings.Contains(results[0].Skipped, "is open") {
+		t.Errorf("expected gardener-controlplane to be skipped, got %+v", results[0])
+	}
+}
+
+func TestBumpConflicts(t *testing.T) {
+	env := newTestEnv(t)
+	// provider-foo is missing in the configuration of the bump repository, and the branch
+	// of a closed pull request bumping gardener-controlplane is left over
+	env.git.addRepo("community/config", "main", []fixtureVersion{{files: map[string]string{"config.yaml": `sources:
+    - name: gardener-controlplane
+      version: v1.0.0
+      repo: gardener/gardener
+`}}})
+	env.git.addBranch("community/config", "chart-releaser/bump-gardener-controlplane-v1.1.0", "main")
+	env.config.Bump = &BumpConfiguration{Owner: "community", Repo: "config"}
+	env.config.SrcCfg[1].Version = "v0.0.9"
+
+	results, err := env.releaser(t.TempDir()).Bump(context.Background(), BumpOptions{})
+	if errs, ok := err.(Errors); !ok || len(errs) != 1 {
+		t.Fatalf("expected provider-foo to fail, got %v", err)
+	}
+	if !strings.Contains(results[1].Error, "config.yaml of community/config does not contain provider-foo") {
+		t.Errorf("unexpected result for provider-foo: %+v", results[1])
+	}
+	pulls := env.gh.pulls["community/config"]
+	if len(pulls) != 1 {
+		t.Fatalf("expected one pull request, got %d", len(pulls))
+	}
+	head := pulls[0].GetHead().GetRef()
+	if !strings.HasPrefix(head, "chart-releaser/bump-gardener-controlplane-v1.1.0-") {
+		t.Errorf("expected a new branch instead of the leftover one, got %s", head)
+	}
+	if config := string(env.git.file("community/config", head, "config.yaml")); !strings.Contains(config, "version: v1.1.0") {
+		t.Errorf("version was not updated:\n%s", config)
+	}
+}
+
+func TestLint(t *testing.T) {
+	env := newTestEnv(t)
+
+	results, err := env.releaser(t.TempDir()).Lint(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 linted sources, got %d", len(results))
+	}
+	for _, result := range results {
+		for _, m := range result.Messages {
+			t.Errorf("%s: unexpected lint message %s", result.Source, m)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	env := newTestEnv(t)
+	// the controller registration of provider-foo lacks the type of its resource
+	env.gh.raw[extensionRepo+"/v0.1.0/example/controller-registration.yaml"] = []byte(
+		strings.Replace(controllerRegistration, "    type: foo\n", "", 1))
+	r := env.releaser(t.TempDir())
+
+	// the cached Kubernetes schemas are used offline
+	schemaDir := filepath.Join(r.opts.CacheDir, "schemas", "v1.24.0-standalone-strict")
+	if err := os.MkdirAll(schemaDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	err := os.WriteFile(filepath.Join(schemaDir, "deployment-apps-v1.json"), []byte(`{
+  "type": "object",
+  "required": ["spec"],
+  "properties": {"spec": {"type": "object", "required": ["selector", "template"]}}
+}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := r.Validate(context.Background(), ValidateOptions{KubernetesVersions: []string{"1.24.0"}, Offline: true})
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 1 {
+		t.Fatalf("expected provider-foo to be invalid, got %v", err)
+	}
+	if len(results) != 2 || results[0].Error != "" || len(results[0].Warnings) != 0 {
+		t.Fatalf("expected gardener-controlplane to be valid, got %+v", results[0])
+	}
+	want := SchemaError{
+		Template: "provider-foo/charts/controller/templates/controller-registration.yaml",
+		Resource: "ControllerRegistration/provider-foo",
+		Field:    "spec.resources.0",
+		Message:  "type is required",
+	}
+	if len(results[1].Errors) != 1 || results[1].Errors[0] != want {
+		t.Errorf("expected %v, got %v", want, results[1].Errors)
+	}
+
+	// versions without cached schemas cannot be validated offline
+	results, err = r.Validate(context.Background(), ValidateOptions{KubernetesVersions: []string{"1.25.0"}, Offline: true})
+	if errs, ok := err.(Errors); !ok || len(errs) != 2 {
+		t.Fatalf("expected both sources to fail without cached schemas, got %v", err)
+	}
+	if !strings.Contains(results[0].Error, "no schemas of Kubernetes 1.25.0 are cached") {
+		t.Errorf("expected the missing schemas in the result, got %q", results[0].Error)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	env := newTestEnv(t)
+	values := filepath.Join(t.TempDir(), "landscape.yaml")
+	err := os.WriteFile(values, []byte(`global:
+  image:
+    tag: v1.1.0-landscape
+controller:
+  enabled: true
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := t.TempDir()
+
+	_, err = env.releaser(t.TempDir()).Template(context.Background(), TemplateOptions{
+		ValuesFiles: []string{values},
+		OutputDir:   out,
+		SplitByKind: true,
+	})
+	if err != nil {
+		t.Fatalf("Template failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(out, "gardener-controlplane", "deployment.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "# Source: gardener-controlplane/templates/deployment.yaml") ||
+		!strings.Contains(string(data), "apiserver:v1.1.0-landscape") {
+		t.Errorf("unexpected deployment manifest:\n%s", data)
+	}
+	// the controller of provider-foo is enabled by the landscape values
+	for _, kind := range []string{"controllerdeployment", "controllerregistration"} {
+		if _, err := os.Stat(filepath.Join(out, "provider-foo", kind+".yaml")); err != nil {
+			t.Errorf("manifest of kind %s is missing: %v", kind, err)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	env := newTestEnv(t)
+
+	diff, err := env.releaser(t.TempDir()).Diff(context.Background(), DiffOptions{
+		Source: "gardener-controlplane",
+		From:   "v1.0.0",
+		To:     "v1.1.0",
+	})
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if len(diff.Resources) != 1 || diff.Resources[0].Resource != "Deployment/gardener-apiserver" || diff.Resources[0].Change != "changed" {
+		t.Fatalf("expected the apiserver deployment to change, got %+v", diff.Resources)
+	}
+	unified := diff.Unified()
+	for _, want := range []string{
+		"--- v1.0.0/Deployment/gardener-apiserver",
+		"+++ v1.1.0/Deployment/gardener-apiserver",
+		"-      - image: eu.gcr.io/gardener-project/gardener/apiserver:v1.0.0",
+		"+      - image: eu.gcr.io/gardener-project/gardener/apiserver:v1.1.0",
+	} {
+		if !strings.Contains(unified, want) {
+			t.Errorf("diff does not contain %q:\n%s", want, unified)
+		}
+	}
+}
+
+func TestUpdateCanceled(t *testing.T) {
+	env := newTestEnv(t)
+	r := env.releaser(t.TempDir())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := r.Update(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a canceled update, got %v", err)
+	}
+	if env.gh.release(destinationRepo, "provider-foo-0.1.0") != nil {
+		t.Error("a release was created after the update was canceled")
+	}
+	entries, err := os.ReadDir(r.opts.WorkDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("workspaces were not cleaned up: %v", entries)
+	}
+}
+
+func TestUpdateFailedChart(t *testing.T) {
+	env := newTestEnv(t)
+	delete(env.gh.raw, extensionRepo+"/v0.1.0/example/controller-registration.yaml")
+
+	report, err := env.releaser(t.TempDir()).Update(context.Background())
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 1 {
+		t.Fatalf("expected one failed chart, got %v", err)
+	}
+	if report.Sources[1].Versions[0].Outcome != OutcomeFailed {
+		t.Errorf("expected failed outcome for provider-foo, got %s", report.Sources[1].Versions[0].Outcome)
+	}
+
+	// the other charts are released nevertheless
+	if !env.git.index(destinationRepo).Has("gardener-controlplane", "1.1.0") {
+		t.Error("index does not contain gardener-controlplane 1.1.0")
+	}
+}
+
+func TestUpdateDestinationFailure(t *testing.T) {
+	env := newTestEnv(t)
+	// the releases of the destination repository cannot be created with the token of the releaser
+	env.gh.private[destinationRepo] = "other-token"
+
+	report, err := env.releaser(t.TempDir()).Update(context.Background())
+	if _, ok := err.(Errors); err == nil || ok {
+		t.Fatalf("expected the destination failure to abort the run, got %v", err)
+	}
+	if !strings.Contains(report.Error, "creating release") {
+		t.Errorf("expected the failed release in the report, got %q", report.Error)
+	}
+	if env.git.index(destinationRepo).Has("gardener-controlplane", "1.1.0") {
+		t.Error("index was updated although no release was created")
+	}
+}
+
+func TestUpdatePrivateSource(t *testing.T) {
+	env := newTestEnv(t)
+	env.gh.private[extensionRepo] = "secret-token"
+
+	_, err := env.releaser(t.TempDir()).Update(context.Background())
+	if errs, ok := err.(Errors); !ok || len(errs) != 1 {
+		t.Fatalf("expected the private source to fail without credentials, got %v", err)
+	}
+
+	t.Setenv("PROVIDER_FOO_TOKEN", "secret-token")
+	env.config.SrcCfg[1].Auth = &AuthConfiguration{Token: SecretRef{Env: "PROVIDER_FOO_TOKEN"}}
+	_, err = env.releaser(t.TempDir()).Update(context.Background())
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if !env.git.index(destinationRepo).Has("provider-foo", "0.1.0") {
+		t.Error("index does not contain provider-foo 0.1.0")
+	}
+}
+
+func TestUpdateGitHubApp(t *testing.T) {
+	env := newTestEnv(t)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.gh.appKey = &key.PublicKey
+	env.gh.installationToken = "installation-token"
+	env.gh.private[destinationRepo] = "installation-token"
+
+	r, err := New(Options{
+		Config:    env.config,
+		TargetDir: t.TempDir(),
+		CacheDir:  t.TempDir(),
+		WorkDir:   t.TempDir(),
+		App: &GitHubApp{
+			AppID:          1,
+			InstallationID: 2,
+			PrivateKey:     pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		},
+		Endpoints: env.gh.endpoints(env.git.root),
+		Logger:    testLogger(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.Update(context.Background())
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if env.gh.asset(destinationRepo, "provider-foo-0.1.0", "provider-foo-0.1.0.tgz") == nil {
+		t.Error("provider-foo 0.1.0 was not uploaded")
+	}
+}
+
+func TestExport(t *testing.T) {
+	env := newTestEnv(t)
+	targetDir := t.TempDir()
+
+	report, err := env.releaser(targetDir).Export(context.Background())
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	for _, s := range report.Sources {
+		if s.Versions[0].Outcome != OutcomeExported {
+			t.Errorf("%s: expected outcome %s, got %s", s.Name, OutcomeExported, s.Versions[0].Outcome)
+		}
+	}
+
+	c, err := loader.Load(filepath.Join(targetDir, "gardener-controlplane"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Metadata.Version != "1.1.0" {
+		t.Errorf("expected version 1.1.0, got %s", c.Metadata.Version)
+	}
+	_, err = os.Stat(filepath.Join(targetDir, "provider-foo", "charts", "controller", "templates", "controller-registration.yaml"))
+	if err != nil {
+		t.Errorf("controller registration was not exported: %v", err)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	env := newTestEnv(t)
+	// the destination repository is private, its assets are only downloaded with the token
+	env.gh.private[destinationRepo] = "test-token"
+
+	// a release whose index update failed and an entry whose asset was deleted
+	env.gh.addRelease(destinationRepo, "provider-foo-0.0.9", "")
+	env.gh.addAsset(destinationRepo, "provider-foo-0.0.9", "provider-foo-0.0.9.tgz", env.publishedPackage("provider-foo", "0.0.9"))
+	env.gh.removeAssets(destinationRepo, "gardener-controlplane-1.0.0")
+
+	result, err := env.releaser(t.TempDir()).Reconcile(context.Background(), ReconcileOptions{Prune: true})
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if len(result.Added) != 1 || result.Added[0] != "provider-foo-0.0.9" {
+		t.Errorf("expected provider-foo-0.0.9 to be added, got %v", result.Added)
+	}
+	if len(result.Stale) != 1 || result.Stale[0] != "gardener-controlplane-1.0.0" {
+		t.Errorf("expected gardener-controlplane-1.0.0 to be stale, got %v", result.Stale)
+	}
+
+	index := env.git.index(destinationRepo)
+	if !index.Has("provider-foo", "0.0.9") {
+		t.Error("index does not contain provider-foo 0.0.9")
+	}
+	if index.Has("gardener-controlplane", "1.0.0") {
+		t.Error("stale entry gardener-controlplane 1.0.0 was not pruned")
+	}
+}
+
+func TestReconcileDryRunAndRebuild(t *testing.T) {
+	env := newTestEnv(t)
+
+	// an index in sync with the release assets is not pushed
+	result, err := env.releaser(t.TempDir()).Reconcile(context.Background(), ReconcileOptions{Prune: true})
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if result.Pushed || len(result.Added) != 0 || len(result.Stale) != 0 {
+		t.Errorf("expected an index in sync, got %+v", result)
+	}
+
+	// a dry run reports the missing entry without pushing it
+	env.gh.addRelease(destinationRepo, "provider-foo-0.0.9", "")
+	env.gh.addAsset(destinationRepo, "provider-foo-0.0.9", "provider-foo-0.0.9.tgz", env.publishedPackage("provider-foo", "0.0.9"))
+	result, err = env.releaser(t.TempDir()).Reconcile(context.Background(), ReconcileOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if result.Pushed || len(result.Added) != 1 || result.Added[0] != "provider-foo-0.0.9" {
+		t.Errorf("expected provider-foo-0.0.9 to be reported only, got %+v", result)
+	}
+	if env.git.index(destinationRepo).Has("provider-foo", "0.0.9") {
+		t.Error("dry run pushed the index")
+	}
+
+	// a rebuild drops the entries without release assets and adds all packages
+	env.gh.removeAssets(destinationRepo, "gardener-controlplane-1.0.0")
+	result, err = env.releaser(t.TempDir()).Reconcile(context.Background(), ReconcileOptions{Rebuild: true})
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if !result.Pushed || len(result.Added) != 1 {
+		t.Errorf("expected the rebuilt index to be pushed with one entry, got %+v", result)
+	}
+	index := env.git.index(destinationRepo)
+	if !index.Has("provider-foo", "0.0.9") || index.Has("gardener-controlplane", "1.0.0") {
+		t.Errorf("expected the rebuilt index to contain only provider-foo 0.0.9, got %v", index.Entries)
+	}
+}
+
+func valuesFile(c *chart.Chart) []byte {
+	for _, f := range c.Raw {
+		if f.Name == "values.yaml" {
+			return f.Data
+		}
+	}
+	return nil
+}
+
+// writeKeyring creates a keyring with a new private key of the identity "Chart Releaser <releaser@example.com>"
+func writeKeyring(t *testing.T) string {
+	entity, err := openpgp.NewEntity("Chart Releaser", "", "releaser@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := entity.SerializePrivate(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "secring.gpg")
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSignAndVerify(t *testing.T) {
+	env := newTestEnv(t)
+	// the destination repository is private, its assets are only downloaded with the token
+	env.gh.private[destinationRepo] = "test-token"
+	keyring := writeKeyring(t)
+	env.config.Signing = &SigningConfiguration{Key: "releaser@example.com", Keyring: keyring}
+
+	report, err := env.releaser(t.TempDir()).Update(context.Background())
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if !report.Sources[1].Versions[0].Signed {
+		t.Error("provider-foo 0.1.0 is not marked as signed in the report")
+	}
+	if env.gh.asset(destinationRepo, "provider-foo-0.1.0", "provider-foo-0.1.0.tgz.prov") == nil {
+		t.Fatal("provenance file of provider-foo 0.1.0 was not uploaded")
+	}
+
+	// the released versions are signed
+	results, err := env.releaser(t.TempDir()).Verify(context.Background(), VerifyOptions{
+		Keyring: keyring,
+		Charts:  []string{"provider-foo", "gardener-controlplane-1.1.0"},
+	})
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 verified charts, got %d", len(results))
+	}
+	for _, result := range results {
+		if !strings.Contains(result.SignedBy, "releaser@example.com") {
+			t.Errorf("%s-%s: unexpected signer %q", result.Name, result.Version, result.SignedBy)
+		}
+	}
+
+	// the version released before has no provenance file and a tampered package fails
+	env.gh.addAsset(destinationRepo, "provider-foo-0.1.0", "provider-foo-0.1.0.tgz", env.publishedPackage("provider-foo", "0.1.0"))
+	_, err = env.releaser(t.TempDir()).Verify(context.Background(), VerifyOptions{Keyring: keyring})
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("expected 2 failed verifications, got %v", err)
+	}
+}
+
+func TestVerifyRebuild(t *testing.T) {
+	env := newTestEnv(t)
+	if _, err := env.releaser(t.TempDir()).Update(context.Background()); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	// the released versions match their rebuilds
+	opts := VerifyOptions{Charts: []string{"provider-foo", "gardener-controlplane-1.1.0"}, Rebuild: true}
+	results, err := env.releaser(t.TempDir()).Verify(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	for _, result := range results {
+		if !result.Rebuilt || len(result.Differences) > 0 {
+			t.Errorf("%s-%s: expected a matching rebuild, got %+v", result.Name, result.Version, result)
+		}
+	}
+
+	// upstream changed after the release
+	env.gh.raw[extensionRepo+"/v0.1.0/example/controller-registration.yaml"] = []byte(
+		strings.Replace(controllerRegistration, "    type: foo\n", "    type: bar\n", 1))
+	results, err = env.releaser(t.TempDir()).Verify(context.Background(), VerifyOptions{Charts: []string{"provider-foo"}, Rebuild: true})
+	if errs, ok := err.(Errors); !ok || len(errs) != 1 {
+		t.Fatalf("expected the rebuild of provider-foo to differ, got %v", err)
+	}
+	if len(results[0].Differences) == 0 {
+		t.Error("expected the differing files to be reported")
+	}
+
+	// a release asset is missing
+	env.gh.removeAssets(destinationRepo, "gardener-controlplane-1.1.0")
+	_, err = env.releaser(t.TempDir()).Verify(context.Background(), VerifyOptions{Charts: []string{"gardener-controlplane-1.1.0"}})
+	if err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("expected the missing release asset to be reported, got %v", err)
+	}
+}
+
+func TestSignExistingRelease(t *testing.T) {
+	env := newTestEnv(t)
+	env.config.Signing = &SigningConfiguration{Key: "releaser@example.com", Keyring: writeKeyring(t)}
+	// the release of provider-foo was created before with a different package
+	env.gh.addRelease(destinationRepo, "provider-foo-0.1.0", "")
+	env.gh.addAsset(destinationRepo, "provider-foo-0.1.0", "provider-foo-0.1.0.tgz", env.publishedPackage("provider-foo", "0.1.0"))
+
+	if _, err := env.releaser(t.TempDir()).Update(context.Background()); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if env.gh.asset(destinationRepo, "provider-foo-0.1.0", "provider-foo-0.1.0.tgz.prov") != nil {
+		t.Error("provenance file was uploaded next to a package it was not signed for")
+	}
+	if env.gh.asset(destinationRepo, "gardener-controlplane-1.1.0", "gardener-controlplane-1.1.0.tgz.prov") == nil {
+		t.Error("provenance file of the new release gardener-controlplane-1.1.0 was not uploaded")
+	}
+}
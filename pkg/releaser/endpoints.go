@@ -0,0 +1,91 @@
+package releaser
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Endpoints are the base URLs used to reach a GitHub instance
+type Endpoints struct {
+	// API is the base URL of the REST API
+	API string
+	// Uploads is the base URL for uploading release assets
+	Uploads string
+	// Raw is the base URL for downloading files of a repository at a given ref
+	Raw string
+	// Download is the base URL of release asset downloads, i.e. <Download><owner>/<repo>/releases/download/<tag>/<file>
+	Download string
+	// Git is the base URL repositories are cloned from, i.e. <Git><owner>/<repo>
+	Git string
+}
+
+// DefaultEndpoints are the endpoints of github.com
+var DefaultEndpoints = Endpoints{
+	API:      "https://api.github.com/",
+	Uploads:  "https://uploads.github.com/",
+	Raw:      "https://raw.githubusercontent.com/",
+	Download: "https://github.com/",
+	Git:      "https://github.com/",
+}
+
+// withDefaults fills unset endpoints with the ones of github.com and makes sure all of them end with a slash
+func (e Endpoints) withDefaults() Endpoints {
+	fill := func(s string, def string) string {
+		if s == "" {
+			return def
+		}
+		if !strings.HasSuffix(s, "/") {
+			s += "/"
+		}
+		return s
+	}
+	return Endpoints{
+		API:      fill(e.API, DefaultEndpoints.API),
+		Uploads:  fill(e.Uploads, DefaultEndpoints.Uploads),
+		Raw:      fill(e.Raw, DefaultEndpoints.Raw),
+		Download: fill(e.Download, DefaultEndpoints.Download),
+		Git:      fill(e.Git, DefaultEndpoints.Git),
+	}
+}
+
+// cloneURL returns the URL the repository "owner/repo" is cloned from
+func (e Endpoints) cloneURL(ownerRepo string) string {
+	return e.Git + ownerRepo
+}
+
+// sshCloneURL returns the ssh URL of the repository "owner/repo" on the host of the git endpoint
+func (e Endpoints) sshCloneURL(ownerRepo string) string {
+	host := "github.com"
+	if u, err := url.Parse(e.Git); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return "ssh://git@" + host + "/" + ownerRepo + ".git"
+}
+
+// rawURL returns the URL of a file of the repository "owner/repo" at ref
+func (e Endpoints) rawURL(ownerRepo string, ref string, file string) string {
+	return e.Raw + ownerRepo + "/" + ref + "/" + file
+}
+
+// releaseDownloadPrefix returns the common prefix of the URLs of all release assets of the repository "owner/repo"
+func (e Endpoints) releaseDownloadPrefix(ownerRepo string) string {
+	return e.Download + ownerRepo + "/releases/download/"
+}
+
+// releaseDownloadURL returns the URL of a release asset of the repository "owner/repo"
+func (e Endpoints) releaseDownloadURL(ownerRepo string, tag string, file string) string {
+	return e.releaseDownloadPrefix(ownerRepo) + tag + "/" + file
+}
+
+// parse parses the API and upload endpoints
+func (e Endpoints) parse() (*url.URL, *url.URL, error) {
+	api, err := url.Parse(e.API)
+	if err != nil {
+		return nil, nil, err
+	}
+	uploads, err := url.Parse(e.Uploads)
+	if err != nil {
+		return nil, nil, err
+	}
+	return api, uploads, nil
+}
@@ -0,0 +1,43 @@
+package releaser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SourceError is returned when a source, or one of its versions, could not be processed
+type SourceError struct {
+	Source  string
+	Version string
+	Err     error
+}
+
+func (e *SourceError) Error() string {
+	if e.Version == "" {
+		return fmt.Sprintf("%s: %v", e.Source, e.Err)
+	}
+	return fmt.Sprintf("%s %s: %v", e.Source, e.Version, e.Err)
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// Errors aggregates the errors of all sources processed during a run
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = "  - " + err.Error()
+	}
+	return fmt.Sprintf("%d chart(s) failed:\n%s", len(e), strings.Join(msgs, "\n"))
+}
+
+// ErrorOrNil returns nil, if no error was collected
+func (e Errors) ErrorOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,31 @@
+package releaser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	cause := errors.New("boom")
+	errs := Errors{
+		&SourceError{Source: "provider-foo", Version: "v0.1.0", Err: cause},
+		&SourceError{Source: "gardener-controlplane", Err: errors.New("listing releases")},
+	}
+
+	msg := errs.Error()
+	for _, want := range []string{"2 chart(s) failed", "  - provider-foo v0.1.0: boom", "  - gardener-controlplane: listing releases"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected %q in error message, got\n%s", want, msg)
+		}
+	}
+	if !errors.Is(errs[0], cause) {
+		t.Error("SourceError does not unwrap to its cause")
+	}
+	if _, ok := errs.ErrorOrNil().(Errors); !ok {
+		t.Error("expected the collected errors")
+	}
+	if Errors(nil).ErrorOrNil() != nil {
+		t.Error("expected no error without collected errors")
+	}
+}
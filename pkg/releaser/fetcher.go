@@ -0,0 +1,52 @@
+package releaser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// Fetcher downloads files, e.g. controller registrations and published chart packages
+type Fetcher interface {
+	// Fetch downloads url, the request is anonymous if creds is nil.
+	// If url does not exist, the error wraps ErrNotFound.
+	Fetch(ctx context.Context, url string, creds *Credentials) ([]byte, error)
+}
+
+// ErrNotFound is returned by Fetch if the file does not exist
+var ErrNotFound = errors.New("not found")
+
+type httpFetcher struct {
+	client *http.Client
+}
+
+// NewFetcher returns a Fetcher which downloads files with client.
+// If client is nil, http.DefaultClient is used.
+func NewFetcher(client *http.Client) Fetcher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &httpFetcher{client: client}
+}
+
+func (f *httpFetcher) Fetch(ctx context.Context, url string, creds *Credentials) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	creds.authorize(req)
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("downloading %s: %w", url, ErrNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading %s: %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
@@ -0,0 +1,36 @@
+package releaser
+
+import "os"
+
+// FileSystem provides the workspaces of the releaser and reads and writes its files.
+// Paths returned by MkdirTemp are handed to helm and git, so they need to exist on the local disk.
+type FileSystem interface {
+	MkdirAll(path string, perm os.FileMode) error
+	MkdirTemp(dir, pattern string) (string, error)
+	RemoveAll(path string) error
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm os.FileMode) error
+}
+
+// OSFileSystem implements FileSystem with the functions of the os package
+type OSFileSystem struct{}
+
+func (OSFileSystem) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (OSFileSystem) MkdirTemp(dir, pattern string) (string, error) {
+	return os.MkdirTemp(dir, pattern)
+}
+
+func (OSFileSystem) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+func (OSFileSystem) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (OSFileSystem) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
@@ -1,48 +1,42 @@
 package releaser
 
 import (
+	"context"
 	"errors"
-	"io/ioutil"
-	"net/http"
 	"strings"
 
-	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 	"helm.sh/helm/v3/pkg/chart"
 )
 
 
-func fetchControllerRegistration(cfg SrcConfiguration) ([]byte, error) {
-	var controller_registration []byte
+func (r *Releaser) fetchControllerRegistration(ctx context.Context, cfg SrcConfiguration) ([]byte, error) {
+	h := r.sourceHost(cfg)
+	e := h.endpoints
 	urls := [4]string{
-		"https://raw.githubusercontent.com/" + cfg.Repo + "/" + cfg.Version + "/examples/controller-registration.yaml",
-		"https://raw.githubusercontent.com/" + cfg.Repo + "/" + cfg.Version + "/example/controller-registration.yaml",
-		"https://raw.githubusercontent.com/" + cfg.Repo + "/" + cfg.Version + "/example/registration/controller-registration.yaml",
-		"https://github.com/" + cfg.Repo + "/releases/download/" + cfg.Version + "/controller-registration.yaml",
+		e.rawURL(cfg.Repo, cfg.Version, "examples/controller-registration.yaml"),
+		e.rawURL(cfg.Repo, cfg.Version, "example/controller-registration.yaml"),
+		e.rawURL(cfg.Repo, cfg.Version, "example/registration/controller-registration.yaml"),
+		e.releaseDownloadURL(cfg.Repo, cfg.Version, "controller-registration.yaml"),
 	}
 
 	for _, url := range urls {
-		resp, err := http.Get(url)
+		controller_registration, err := r.opts.Fetcher.Fetch(ctx, url, h.creds)
 		if err == nil {
-			if resp.StatusCode == 200 {
-				defer resp.Body.Close()
-				controller_registration, err = ioutil.ReadAll(resp.Body)
-				if err == nil {
-					// Download was sucessful, return content
-					logrus.Info("Successfully fetched chart for ", cfg.Name, " URL: ", url)
-					return controller_registration, nil
-				}
-			}
+			// Download was sucessful, return content
+			r.log.Info("Successfully fetched chart for ", cfg.Name, " URL: ", url)
+			return controller_registration, nil
 		}
+		r.log.Debug(err)
 	}
 	return nil, errors.New("Was not able to fetch chart for " + cfg.Name)
 }
 
 
-func generateExtensionChart(cfg SrcConfiguration) chart.Chart {
-	controller_registration, err := fetchControllerRegistration(cfg)
+func (r *Releaser) generateExtensionChart(ctx context.Context, cfg SrcConfiguration) (chart.Chart, error) {
+	controller_registration, err := r.fetchControllerRegistration(ctx, cfg)
 	if err != nil {
-		logrus.Warn(err.Error())
+		return chart.Chart{}, err
 	}
 	// add a helm template for values in the ControllerDeployment part of the chart
 	controller_registration_as_string := string(controller_registration)
@@ -61,7 +55,10 @@ func generateExtensionChart(cfg SrcConfiguration) chart.Chart {
 	// to create an empty values file:
 	var values = make(map[string]interface{})
 	values["values"] = map[string]interface{}{}
-	values_serialized, _ := yaml.Marshal(values)
+	values_serialized, err := yaml.Marshal(values)
+	if err != nil {
+		return chart.Chart{}, err
+	}
 
 	controller_chart := chart.Chart{
 		Metadata: &chart.Metadata{
@@ -81,6 +78,6 @@ func generateExtensionChart(cfg SrcConfiguration) chart.Chart {
 		}},
 	}
 
-	return controller_chart
+	return controller_chart, nil
 
 }
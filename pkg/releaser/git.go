@@ -0,0 +1,226 @@
+package releaser
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"golang.org/x/oauth2"
+)
+
+// Git performs the git operations of the releaser
+type Git interface {
+	// Checkout clones url into dir, or fetches it if dir already contains a clone,
+	// and checks out tag. It returns the hash of the commit the tag points to.
+	// Clones and fetches are anonymous if creds is nil.
+	Checkout(ctx context.Context, url string, dir string, tag string, creds *Credentials) (string, error)
+	// Clone clones branch of url into dir. The clone is authenticated like pushes, so
+	// private repositories the releaser pushes to can be cloned as well.
+	Clone(ctx context.Context, url string, dir string, branch string) error
+	// Branch creates a branch from the checked out commit of the clone in dir and checks it out
+	Branch(ctx context.Context, dir string, branch string) error
+	// RemoteBranchExists reports whether the repository the clone in dir was cloned from has the branch
+	RemoteBranchExists(ctx context.Context, dir string, branch string) (bool, error)
+	// CommitAndPush commits files of the clone in dir and pushes the checked out branch
+	CommitAndPush(ctx context.Context, dir string, message string, files ...string) error
+}
+
+type goGit struct {
+	tokens oauth2.TokenSource
+}
+
+// NewGit returns a Git implementation based on go-git. Clones and pushes are authenticated with the github token.
+func NewGit(ghToken string) Git {
+	return NewGitWithTokenSource(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: ghToken}))
+}
+
+// NewGitWithTokenSource returns a Git implementation based on go-git. Clones and pushes are authenticated
+// with a fresh token of ts, e.g. an installation token of a GitHub App.
+func NewGitWithTokenSource(ts oauth2.TokenSource) Git {
+	return &goGit{tokens: ts}
+}
+
+func (g *goGit) Checkout(ctx context.Context, url string, dir string, tag string, creds *Credentials) (string, error) {
+	auth, err := creds.gitAuth(strings.HasPrefix(url, "ssh://"))
+	if err != nil {
+		return "", err
+	}
+
+	// Clone the repository or open it, if it already exists on disk
+	// It is handeled like this for performance reasons, when e.g. exporting the charts
+	repo, err := git.PlainCloneContext(ctx, dir, false, &git.CloneOptions{
+		URL:  url,
+		Auth: auth,
+		Tags: git.AllTags,
+	})
+	if err == git.ErrRepositoryAlreadyExists {
+		repo, err = git.PlainOpen(dir)
+		if err != nil {
+			return "", err
+		}
+		// fetch from url instead of origin, the clone may have been created with other credentials
+		var remote *git.Remote
+		remote, err = repo.CreateRemoteAnonymous(&config.RemoteConfig{
+			Name: "anonymous",
+			URLs: []string{url},
+		})
+		if err != nil {
+			return "", err
+		}
+		err = remote.FetchContext(ctx, &git.FetchOptions{
+			RemoteName: "anonymous",
+			RefSpecs:   []config.RefSpec{"+refs/tags/*:refs/tags/*"},
+			Auth:       auth,
+			Tags:       git.AllTags,
+			Force:      true,
+		})
+		if err == git.NoErrAlreadyUpToDate {
+			err = nil
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	ref, err := repo.Tag(tag)
+	if err != nil {
+		return "", err
+	}
+	// annotated tags point to a tag object instead of a commit
+	hash := ref.Hash()
+	if tagObject, err := repo.TagObject(hash); err == nil {
+		commit, err := tagObject.Commit()
+		if err != nil {
+			return "", err
+		}
+		hash = commit.Hash
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	err = wt.Checkout(&git.CheckoutOptions{
+		Hash:  hash,
+		Force: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	return hash.String(), nil
+}
+
+func (g *goGit) Clone(ctx context.Context, url string, dir string, branch string) error {
+	auth, err := g.auth()
+	if err != nil {
+		return err
+	}
+	_, err = git.PlainCloneContext(ctx, dir, false, &git.CloneOptions{
+		URL:           url,
+		Auth:          auth,
+		ReferenceName: plumbing.NewBranchReferenceName(branch),
+		SingleBranch:  true,
+	})
+	return err
+}
+
+func (g *goGit) Branch(ctx context.Context, dir string, branch string) error {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	return wt.Checkout(&git.CheckoutOptions{
+		Branch: plumbing.NewBranchReferenceName(branch),
+		Create: true,
+		Keep:   true,
+	})
+}
+
+func (g *goGit) RemoteBranchExists(ctx context.Context, dir string, branch string) (bool, error) {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return false, err
+	}
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return false, err
+	}
+	auth, err := g.auth()
+	if err != nil {
+		return false, err
+	}
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: auth})
+	if err != nil {
+		return false, err
+	}
+	for _, ref := range refs {
+		if ref.Name() == plumbing.NewBranchReferenceName(branch) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (g *goGit) CommitAndPush(ctx context.Context, dir string, message string, files ...string) error {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if _, err := wt.Add(f); err != nil {
+			return err
+		}
+	}
+	_, err = wt.Commit(message, &git.CommitOptions{
+		Author: &object.Signature{
+			Name:  "gardener-chart-releaser",
+			Email: "gardener-chart-releaser@users.noreply.github.com",
+			When:  time.Now(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	auth, err := g.auth()
+	if err != nil {
+		return err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+	return repo.PushContext(ctx, &git.PushOptions{
+		RefSpecs: []config.RefSpec{config.RefSpec(head.Name() + ":" + head.Name())},
+		Auth:     auth,
+	})
+}
+
+// auth returns the auth method for clones and pushes of the repositories the releaser writes to,
+// git operations are anonymous without a token
+func (g *goGit) auth() (transport.AuthMethod, error) {
+	token, err := g.tokens.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		return nil, nil
+	}
+	return &githttp.BasicAuth{
+		Username: "x-access-token",
+		Password: token.AccessToken,
+	}, nil
+}
@@ -0,0 +1,36 @@
+package releaser
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestGitCloneAuth(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		users []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		user, password, _ := req.BasicAuth()
+		mu.Lock()
+		users = append(users, user+":"+password)
+		mu.Unlock()
+		http.Error(w, "not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	for token, want := range map[string]string{"t0ken": "x-access-token:t0ken", "": ":"} {
+		users = nil
+		err := NewGit(token).Clone(context.Background(), server.URL+"/owner/repo.git", filepath.Join(t.TempDir(), "repo"), "gh-pages")
+		if err == nil {
+			t.Fatal("expected the clone to fail")
+		}
+		if len(users) == 0 || users[0] != want {
+			t.Errorf("token %q: expected credentials %q, got %v", token, want, users)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package releaser
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v36/github"
+	"golang.org/x/oauth2"
+)
+
+// GitHub is the subset of the GitHub API used by the releaser.
+// It is implemented by the Repositories service of a *github.Client.
+type GitHub interface {
+	ListReleases(ctx context.Context, owner, repo string, opts *github.ListOptions) ([]*github.RepositoryRelease, *github.Response, error)
+	GetReleaseByTag(ctx context.Context, owner, repo, tag string) (*github.RepositoryRelease, *github.Response, error)
+	GetLatestRelease(ctx context.Context, owner, repo string) (*github.RepositoryRelease, *github.Response, error)
+	GetCommit(ctx context.Context, owner, repo, sha string) (*github.RepositoryCommit, *github.Response, error)
+	CreateRelease(ctx context.Context, owner, repo string, release *github.RepositoryRelease) (*github.RepositoryRelease, *github.Response, error)
+	UploadReleaseAsset(ctx context.Context, owner, repo string, id int64, opts *github.UploadOptions, file *os.File) (*github.ReleaseAsset, *github.Response, error)
+}
+
+// NewGitHubClient returns a *github.Client for the github token talking to the given endpoints
+// this client will be used for interacting with the github api
+func NewGitHubClient(ctx context.Context, ghToken string, endpoints Endpoints) (*github.Client, error) {
+	var ts oauth2.TokenSource
+	if ghToken != "" {
+		ts = oauth2.StaticTokenSource(
+			&oauth2.Token{AccessToken: ghToken},
+		)
+	}
+	return NewGitHubClientFromTokenSource(ctx, ts, endpoints)
+}
+
+// NewGitHubClientFromTokenSource returns a *github.Client authenticated with the tokens of ts,
+// e.g. the installation tokens of a GitHub App. If ts is nil, the client is anonymous.
+// Like oauth2.NewClient, it uses the *http.Client stored in ctx under oauth2.HTTPClient.
+func NewGitHubClientFromTokenSource(ctx context.Context, ts oauth2.TokenSource, endpoints Endpoints) (*github.Client, error) {
+	api, uploads, err := endpoints.withDefaults().parse()
+	if err != nil {
+		return nil, err
+	}
+
+	var client *github.Client
+	if ts == nil {
+		httpClient, _ := ctx.Value(oauth2.HTTPClient).(*http.Client)
+		client = github.NewClient(httpClient)
+	} else {
+		client = github.NewClient(oauth2.NewClient(ctx, ts))
+	}
+	client.BaseURL = api
+	client.UploadURL = uploads
+	return client, nil
+}
+
+// splitRepo splits "owner/repo" into its parts
+func splitRepo(ownerRepo string) (string, string) {
+	parts := strings.SplitN(ownerRepo, "/", 2)
+	if len(parts) < 2 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// PullRequests is the subset of the GitHub API used to propose index updates as pull requests.
+// It is implemented by NewPullRequests for a *github.Client.
+type PullRequests interface {
+	Create(ctx context.Context, owner, repo string, pull *github.NewPullRequest) (*github.PullRequest, *github.Response, error)
+	RequestReviewers(ctx context.Context, owner, repo string, number int, reviewers github.ReviewersRequest) (*github.PullRequest, *github.Response, error)
+	AddLabelsToIssue(ctx context.Context, owner, repo string, number int, labels []string) ([]*github.Label, *github.Response, error)
+	List(ctx context.Context, owner, repo string, opts *github.PullRequestListOptions) ([]*github.PullRequest, *github.Response, error)
+}
+
+type pullRequests struct {
+	pulls  *github.PullRequestsService
+	issues *github.IssuesService
+}
+
+// NewPullRequests returns the PullRequests implementation of a *github.Client
+func NewPullRequests(client *github.Client) PullRequests {
+	return &pullRequests{pulls: client.PullRequests, issues: client.Issues}
+}
+
+func (p *pullRequests) Create(ctx context.Context, owner, repo string, pull *github.NewPullRequest) (*github.PullRequest, *github.Response, error) {
+	return p.pulls.Create(ctx, owner, repo, pull)
+}
+
+func (p *pullRequests) RequestReviewers(ctx context.Context, owner, repo string, number int, reviewers github.ReviewersRequest) (*github.PullRequest, *github.Response, error) {
+	return p.pulls.RequestReviewers(ctx, owner, repo, number, reviewers)
+}
+
+// AddLabelsToIssue labels a pull request, which is an issue as well
+func (p *pullRequests) AddLabelsToIssue(ctx context.Context, owner, repo string, number int, labels []string) ([]*github.Label, *github.Response, error) {
+	return p.issues.AddLabelsToIssue(ctx, owner, repo, number, labels)
+}
+
+func (p *pullRequests) List(ctx context.Context, owner, repo string, opts *github.PullRequestListOptions) ([]*github.PullRequest, *github.Response, error) {
+	return p.pulls.List(ctx, owner, repo, opts)
+}
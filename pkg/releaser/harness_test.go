@@ -0,0 +1,507 @@
+package releaser
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/google/go-github/v36/github"
+	"github.com/sirupsen/logrus"
+	"helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/yaml"
+)
+
+// fakeGitHub serves the parts of the GitHub API, the upload API, raw file downloads and
+// release asset downloads used by the releaser from memory
+type fakeGitHub struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu       sync.Mutex
+	nextID   int64
+	releases map[string][]*github.RepositoryRelease // by "owner/repo"
+	assets   map[string][]byte                      // by "owner/repo/releases/download/tag/name"
+	raw      map[string][]byte                      // by "owner/repo/ref/path"
+	commits  map[string]string                      // by "owner/repo@ref"
+	private  map[string]string                      // tokens of private repositories by "owner/repo"
+	pulls    map[string][]*github.PullRequest       // by "owner/repo"
+
+	// appKey verifies the JWTs of the GitHub App, which gets installationToken
+	appKey            *rsa.PublicKey
+	installationToken string
+}
+
+func newFakeGitHub(t *testing.T) *fakeGitHub {
+	f := &fakeGitHub{
+		t:        t,
+		nextID:   1,
+		releases: map[string][]*github.RepositoryRelease{},
+		assets:   map[string][]byte{},
+		raw:      map[string][]byte{},
+		commits:  map[string]string{},
+		private:  map[string]string{},
+		pulls:    map[string][]*github.PullRequest{},
+	}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// endpoints returns the endpoints of the fake, repositories are cloned from gitRoot
+func (f *fakeGitHub) endpoints(gitRoot string) Endpoints {
+	return Endpoints{
+		API:      f.server.URL + "/api/",
+		Uploads:  f.server.URL + "/uploads/",
+		Raw:      f.server.URL + "/raw/",
+		Download: f.server.URL + "/download/",
+		Git:      "file://" + gitRoot + "/",
+	}
+}
+
+// addRelease adds a release with the given tag and body to the repository "owner/repo"
+func (f *fakeGitHub) addRelease(ownerRepo string, tag string, body string) *github.RepositoryRelease {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	rel := &github.RepositoryRelease{
+		ID:        github.Int64(f.nextID),
+		Name:      github.String(tag),
+		TagName:   github.String(tag),
+		Body:      github.String(body),
+		HTMLURL:   github.String(f.server.URL + "/web/" + ownerRepo + "/releases/tag/" + tag),
+		CreatedAt: &github.Timestamp{Time: time.Now()},
+	}
+	f.nextID++
+	// the API lists the newest release first
+	f.releases[ownerRepo] = append([]*github.RepositoryRelease{rel}, f.releases[ownerRepo]...)
+	return rel
+}
+
+// addAsset attaches a file to the release with the given tag
+func (f *fakeGitHub) addAsset(ownerRepo string, tag string, name string, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, rel := range f.releases[ownerRepo] {
+		if rel.GetTagName() == tag {
+			f.attach(ownerRepo, rel, name, data)
+			return
+		}
+	}
+	f.t.Fatalf("release %s of %s does not exist", tag, ownerRepo)
+}
+
+func (f *fakeGitHub) attach(ownerRepo string, rel *github.RepositoryRelease, name string, data []byte) *github.ReleaseAsset {
+	key := ownerRepo + "/releases/download/" + rel.GetTagName() + "/" + name
+	f.assets[key] = data
+	asset := &github.ReleaseAsset{
+		ID:                 github.Int64(f.nextID),
+		Name:               github.String(name),
+		BrowserDownloadURL: github.String(f.server.URL + "/download/" + key),
+	}
+	f.nextID++
+	rel.Assets = append(rel.Assets, asset)
+	return asset
+}
+
+// removeAssets deletes all assets of the release with the given tag
+func (f *fakeGitHub) removeAssets(ownerRepo string, tag string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, rel := range f.releases[ownerRepo] {
+		if rel.GetTagName() == tag {
+			for _, a := range rel.Assets {
+				delete(f.assets, ownerRepo+"/releases/download/"+tag+"/"+a.GetName())
+			}
+			rel.Assets = nil
+		}
+	}
+}
+
+// release returns the release with the given tag or nil
+func (f *fakeGitHub) release(ownerRepo string, tag string) *github.RepositoryRelease {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, rel := range f.releases[ownerRepo] {
+		if rel.GetTagName() == tag {
+			return rel
+		}
+	}
+	return nil
+}
+
+// asset returns the content of a release asset
+func (f *fakeGitHub) asset(ownerRepo string, tag string, name string) []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.assets[ownerRepo+"/releases/download/"+tag+"/"+name]
+}
+
+func (f *fakeGitHub) serve(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	parts := strings.Split(strings.TrimPrefix(req.URL.Path, "/"), "/")
+	if !f.authorized(req, parts) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+		return
+	}
+	switch {
+	case parts[0] == "api" && len(parts) == 5 && parts[1] == "app" && parts[4] == "access_tokens":
+		f.serveInstallationToken(w, req)
+	case parts[0] == "api" && len(parts) >= 5 && parts[1] == "repos":
+		f.serveAPI(w, req, parts[2]+"/"+parts[3], parts[4:])
+	case parts[0] == "uploads" && len(parts) == 7 && parts[1] == "repos":
+		f.serveUpload(w, req, parts[2]+"/"+parts[3], parts[5])
+	case parts[0] == "raw" && len(parts) >= 5:
+		f.serveFile(w, f.raw, strings.Join(parts[1:], "/"))
+	case parts[0] == "download":
+		f.serveFile(w, f.assets, strings.Join(parts[1:], "/"))
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// authorized reports whether the request carries the token of a private repository
+func (f *fakeGitHub) authorized(req *http.Request, parts []string) bool {
+	var ownerRepo string
+	switch {
+	case (parts[0] == "api" || parts[0] == "uploads") && len(parts) >= 4 && parts[1] == "repos":
+		ownerRepo = parts[2] + "/" + parts[3]
+	case (parts[0] == "raw" || parts[0] == "download") && len(parts) >= 3:
+		ownerRepo = parts[1] + "/" + parts[2]
+	}
+	token, ok := f.private[ownerRepo]
+	auth := req.Header.Get("Authorization")
+	return !ok || auth == "token "+token || auth == "Bearer "+token
+}
+
+// serveInstallationToken verifies the JWT of the app and returns its installation token
+func (f *fakeGitHub) serveInstallationToken(w http.ResponseWriter, req *http.Request) {
+	jwt := strings.Split(strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "), ".")
+	if f.appKey == nil || len(jwt) != 3 {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Bad credentials"})
+		return
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(jwt[2])
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"message": err.Error()})
+		return
+	}
+	hash := sha256.Sum256([]byte(jwt[0] + "." + jwt[1]))
+	if err := rsa.VerifyPKCS1v15(f.appKey, crypto.SHA256, hash[:], signature); err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"message": err.Error()})
+		return
+	}
+	expiresAt := time.Now().Add(time.Hour)
+	writeJSON(w, http.StatusCreated, &github.InstallationToken{
+		Token:     github.String(f.installationToken),
+		ExpiresAt: &expiresAt,
+	})
+}
+
+func (f *fakeGitHub) serveAPI(w http.ResponseWriter, req *http.Request, ownerRepo string, parts []string) {
+	switch {
+	case req.Method == http.MethodGet && len(parts) == 1 && parts[0] == "releases":
+		writeJSON(w, http.StatusOK, f.releases[ownerRepo])
+	case req.Method == http.MethodPost && len(parts) == 1 && parts[0] == "releases":
+		rel := &github.RepositoryRelease{}
+		if err := json.NewDecoder(req.Body).Decode(rel); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+			return
+		}
+		for _, existing := range f.releases[ownerRepo] {
+			if existing.GetTagName() == rel.GetTagName() {
+				writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "already_exists"})
+				return
+			}
+		}
+		rel.ID = github.Int64(f.nextID)
+		f.nextID++
+		rel.HTMLURL = github.String(f.server.URL + "/web/" + ownerRepo + "/releases/tag/" + rel.GetTagName())
+		rel.CreatedAt = &github.Timestamp{Time: time.Now()}
+		f.releases[ownerRepo] = append([]*github.RepositoryRelease{rel}, f.releases[ownerRepo]...)
+		writeJSON(w, http.StatusCreated, rel)
+	case req.Method == http.MethodGet && len(parts) == 2 && parts[1] == "latest":
+		if len(f.releases[ownerRepo]) == 0 {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, f.releases[ownerRepo][0])
+	case req.Method == http.MethodGet && len(parts) == 3 && parts[1] == "tags":
+		for _, rel := range f.releases[ownerRepo] {
+			if rel.GetTagName() == parts[2] {
+				writeJSON(w, http.StatusOK, rel)
+				return
+			}
+		}
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+	case req.Method == http.MethodGet && len(parts) == 1 && parts[0] == "pulls":
+		// all pull requests of the fake are open
+		writeJSON(w, http.StatusOK, f.pulls[ownerRepo])
+	case req.Method == http.MethodPost && len(parts) == 1 && parts[0] == "pulls":
+		newPull := &github.NewPullRequest{}
+		if err := json.NewDecoder(req.Body).Decode(newPull); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+			return
+		}
+		number := len(f.pulls[ownerRepo]) + 1
+		pull := &github.PullRequest{
+			Number:  github.Int(number),
+			Title:   newPull.Title,
+			Body:    newPull.Body,
+			Head:    &github.PullRequestBranch{Ref: newPull.Head},
+			Base:    &github.PullRequestBranch{Ref: newPull.Base},
+			HTMLURL: github.String(f.server.URL + "/web/" + ownerRepo + "/pull/" + strconv.Itoa(number)),
+		}
+		f.pulls[ownerRepo] = append(f.pulls[ownerRepo], pull)
+		writeJSON(w, http.StatusCreated, pull)
+	case req.Method == http.MethodPost && len(parts) == 3 && parts[0] == "pulls" && parts[2] == "requested_reviewers":
+		pull := f.pull(ownerRepo, parts[1])
+		reviewers := github.ReviewersRequest{}
+		if pull == nil || json.NewDecoder(req.Body).Decode(&reviewers) != nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+		for _, login := range reviewers.Reviewers {
+			pull.RequestedReviewers = append(pull.RequestedReviewers, &github.User{Login: github.String(login)})
+		}
+		for _, slug := range reviewers.TeamReviewers {
+			pull.RequestedTeams = append(pull.RequestedTeams, &github.Team{Slug: github.String(slug)})
+		}
+		writeJSON(w, http.StatusCreated, pull)
+	case req.Method == http.MethodPost && len(parts) == 3 && parts[0] == "issues" && parts[2] == "labels":
+		pull := f.pull(ownerRepo, parts[1])
+		var labels []string
+		if pull == nil || json.NewDecoder(req.Body).Decode(&labels) != nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+		for _, name := range labels {
+			pull.Labels = append(pull.Labels, &github.Label{Name: github.String(name)})
+		}
+		writeJSON(w, http.StatusOK, pull.Labels)
+	case req.Method == http.MethodGet && len(parts) == 2 && parts[0] == "commits":
+		sha, ok := f.commits[ownerRepo+"@"+parts[1]]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, &github.RepositoryCommit{
+			SHA: github.String(sha),
+			Commit: &github.Commit{
+				Committer: &github.CommitAuthor{Date: &testSignature.When},
+			},
+		})
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+	}
+}
+
+// pull returns the pull request with the given number, the caller holds the lock
+func (f *fakeGitHub) pull(ownerRepo string, number string) *github.PullRequest {
+	for _, pull := range f.pulls[ownerRepo] {
+		if strconv.Itoa(pull.GetNumber()) == number {
+			return pull
+		}
+	}
+	return nil
+}
+
+func (f *fakeGitHub) serveUpload(w http.ResponseWriter, req *http.Request, ownerRepo string, id string) {
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+	for _, rel := range f.releases[ownerRepo] {
+		if strconv.FormatInt(rel.GetID(), 10) == id {
+			writeJSON(w, http.StatusCreated, f.attach(ownerRepo, rel, req.URL.Query().Get("name"), data))
+			return
+		}
+	}
+	writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+}
+
+func (f *fakeGitHub) serveFile(w http.ResponseWriter, files map[string][]byte, key string) {
+	data, ok := files[key]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Write(data)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// gitFixture creates bare git repositories below root, which are cloned via the file protocol
+type gitFixture struct {
+	t    *testing.T
+	root string
+}
+
+func newGitFixture(t *testing.T) *gitFixture {
+	return &gitFixture{t: t, root: t.TempDir()}
+}
+
+var testSignature = &object.Signature{Name: "test", Email: "test@example.com", When: time.Unix(1660000000, 0)}
+
+// addRepo creates the bare repository "owner/repo" with one commit per version on the given branch.
+// Each version is tagged, every second tag is annotated. It returns the commit hashes by tag.
+func (g *gitFixture) addRepo(ownerRepo string, branch string, versions []fixtureVersion) map[string]string {
+	t := g.t
+	work := t.TempDir()
+	r, err := git.PlainInit(work, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName(branch)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commits := map[string]string{}
+	for i, v := range versions {
+		for name, content := range v.files {
+			path := filepath.Join(work, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := wt.Add(name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		hash, err := wt.Commit("Release "+v.tag, &git.CommitOptions{Author: testSignature})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.tag != "" {
+			var opts *git.CreateTagOptions
+			if i%2 == 1 {
+				opts = &git.CreateTagOptions{Tagger: testSignature, Message: v.tag}
+			}
+			if _, err := r.CreateTag(v.tag, hash, opts); err != nil {
+				t.Fatal(err)
+			}
+			commits[v.tag] = hash.String()
+		}
+	}
+
+	bare := filepath.Join(g.root, ownerRepo)
+	if _, err := git.PlainInit(bare, true); err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{bare}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Push(&git.PushOptions{RefSpecs: []config.RefSpec{"refs/heads/*:refs/heads/*", "refs/tags/*:refs/tags/*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return commits
+}
+
+// addBranch creates a branch of the bare repository "owner/repo" pointing to the head of another branch
+func (g *gitFixture) addBranch(ownerRepo string, branch string, from string) {
+	t := g.t
+	r, err := git.PlainOpen(filepath.Join(g.root, ownerRepo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := r.Reference(plumbing.NewBranchReferenceName(from), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(branch), ref.Hash()))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// file returns the content of a file on a branch of the bare repository "owner/repo"
+func (g *gitFixture) file(ownerRepo string, branch string, name string) []byte {
+	t := g.t
+	r, err := git.PlainOpen(filepath.Join(g.root, ownerRepo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := r.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := r.CommitObject(ref.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := commit.File(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := f.Contents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []byte(content)
+}
+
+// index returns the index.yaml on the pages branch of the bare repository "owner/repo"
+func (g *gitFixture) index(ownerRepo string) *repo.IndexFile {
+	return g.indexOn(ownerRepo, pagesBranch)
+}
+
+// indexOn returns the index.yaml on a branch of the bare repository "owner/repo"
+func (g *gitFixture) indexOn(ownerRepo string, branch string) *repo.IndexFile {
+	index := &repo.IndexFile{}
+	if err := yaml.Unmarshal(g.file(ownerRepo, branch, "index.yaml"), index); err != nil {
+		g.t.Fatal(err)
+	}
+	return index
+}
+
+// fixtureVersion is a commit of a fixture repository, the files are added or overwritten
+type fixtureVersion struct {
+	tag   string
+	files map[string]string
+}
+
+// testLogger returns a logger writing to the test log
+func testLogger(t *testing.T) logrus.FieldLogger {
+	logger := logrus.New()
+	logger.SetOutput(testLogWriter{t})
+	return logger
+}
+
+type testLogWriter struct {
+	t *testing.T
+}
+
+func (w testLogWriter) Write(p []byte) (int, error) {
+	w.t.Log(strings.TrimSpace(string(p)))
+	return len(p), nil
+}
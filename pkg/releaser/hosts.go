@@ -0,0 +1,151 @@
+package releaser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// host is a GitHub instance sources or the destination live on, together with
+// the credentials used to access the repositories of a source
+type host struct {
+	endpoints Endpoints
+	github    GitHub
+	pulls     PullRequests
+	creds     *Credentials
+}
+
+// cloneURL returns the URL the repository "owner/repo" is cloned from, ssh is used if an SSH key is configured
+func (h *host) cloneURL(ownerRepo string) string {
+	if h.creds != nil && len(h.creds.SSHKey) > 0 {
+		return h.endpoints.sshCloneURL(ownerRepo)
+	}
+	return h.endpoints.cloneURL(ownerRepo)
+}
+
+// EndpointsForHost returns the endpoints of a GitHub Enterprise Server host, e.g. "github.example.com".
+// The host may be prefixed with a scheme, https is used otherwise. An empty host and "github.com"
+// result in the endpoints of github.com.
+func EndpointsForHost(name string) Endpoints {
+	base := normalizeHost(name)
+	if base == "" || base == "github.com" {
+		return DefaultEndpoints
+	}
+	if !strings.Contains(base, "://") {
+		base = "https://" + base
+	}
+	base += "/"
+	return Endpoints{
+		API:      base + "api/v3/",
+		Uploads:  base + "api/uploads/",
+		Raw:      base + "raw/",
+		Download: base,
+		Git:      base,
+	}
+}
+
+// normalizeHost strips trailing slashes and the https scheme from a host
+func normalizeHost(name string) string {
+	return strings.TrimPrefix(strings.TrimRight(name, "/"), "https://")
+}
+
+// initHosts creates the GitHub clients for all hosts of the configuration.
+// Sources and the destination without a host use the endpoints and the GitHub client of the options.
+// Only the client of the destination host is authenticated with the token source of the options,
+// the clients of other hosts are anonymous.
+func (r *Releaser) initHosts(ctx context.Context) error {
+	r.hosts = map[string]*host{
+		"": {endpoints: r.opts.Endpoints, github: r.opts.GitHub, pulls: r.opts.PullRequests},
+	}
+	names := []string{r.opts.Config.DstCfg.Host}
+	if r.opts.Config.Bump != nil {
+		names = append(names, r.opts.Config.Bump.Host)
+	}
+	for _, cfg := range r.opts.Config.SrcCfg {
+		names = append(names, cfg.Host)
+	}
+	for _, name := range names {
+		key := hostKey(name)
+		if _, ok := r.hosts[key]; ok {
+			continue
+		}
+		endpoints := EndpointsForHost(name)
+		var ts oauth2.TokenSource
+		if key == hostKey(r.opts.Config.DstCfg.Host) {
+			ts = r.opts.TokenSource
+		}
+		client, err := NewGitHubClientFromTokenSource(ctx, ts, endpoints)
+		if err != nil {
+			return fmt.Errorf("creating GitHub client for %s: %w", name, err)
+		}
+		r.hosts[key] = &host{endpoints: endpoints, github: client.Repositories, pulls: NewPullRequests(client)}
+	}
+
+	// sources with credentials get a host of their own
+	r.sourceHosts = map[string]*host{}
+	for _, cfg := range r.opts.Config.SrcCfg {
+		creds, err := cfg.Auth.Credentials()
+		if err != nil {
+			return fmt.Errorf("credentials of source %s: %w", cfg.Name, err)
+		}
+		if creds == nil {
+			continue
+		}
+		h := *r.host(cfg.Host)
+		h.creds = creds
+		if creds.hasAPIAuth() {
+			client, err := newGitHubClientWithCredentials(ctx, creds, h.endpoints)
+			if err != nil {
+				return fmt.Errorf("creating GitHub client for source %s: %w", cfg.Name, err)
+			}
+			h.github = client.Repositories
+		}
+		r.sourceHosts[cfg.Name] = &h
+	}
+	return nil
+}
+
+// sourceHost returns the GitHub instance and the credentials of a source
+func (r *Releaser) sourceHost(cfg SrcConfiguration) *host {
+	if h, ok := r.sourceHosts[cfg.Name]; ok {
+		return h
+	}
+	return r.host(cfg.Host)
+}
+
+// assetCredentials returns the credentials for downloading a release asset. Only assets of the
+// destination repository are downloaded with its token, other URLs are downloaded anonymously.
+func (r *Releaser) assetCredentials(url string) (*Credentials, error) {
+	dst := r.opts.Config.DstCfg
+	prefix := r.host(dst.Host).endpoints.releaseDownloadPrefix(dst.Owner + "/" + dst.Repo)
+	if r.opts.TokenSource == nil || !strings.HasPrefix(url, prefix) {
+		return nil, nil
+	}
+	token, err := r.opts.TokenSource.Token()
+	if err != nil {
+		return nil, err
+	}
+	return &Credentials{Token: token.AccessToken}, nil
+}
+
+// host returns the GitHub instance with the given name
+func (r *Releaser) host(name string) *host {
+	if h, ok := r.hosts[hostKey(name)]; ok {
+		return h
+	}
+	// hosts are created for all configured sources, this only happens for sources
+	// which are not part of the configuration and falls back to the default
+	r.log.Warn("Unknown host ", name, ", using the default endpoints")
+	return r.hosts[""]
+}
+
+// hostKey maps the names of a host to the same key, github.com is the default host
+func hostKey(name string) string {
+	key := normalizeHost(name)
+	if key == "github.com" {
+		return ""
+	}
+	return key
+}
@@ -0,0 +1,123 @@
+package releaser
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEndpointsForHost(t *testing.T) {
+	enterprise := Endpoints{
+		API:      "https://github.example.com/api/v3/",
+		Uploads:  "https://github.example.com/api/uploads/",
+		Raw:      "https://github.example.com/raw/",
+		Download: "https://github.example.com/",
+		Git:      "https://github.example.com/",
+	}
+	for _, tc := range []struct {
+		host string
+		want Endpoints
+	}{
+		{"", DefaultEndpoints},
+		{"github.com", DefaultEndpoints},
+		{"https://github.com/", DefaultEndpoints},
+		{"github.example.com", enterprise},
+		{"https://github.example.com/", enterprise},
+		{"http://localhost:8080", Endpoints{
+			API:      "http://localhost:8080/api/v3/",
+			Uploads:  "http://localhost:8080/api/uploads/",
+			Raw:      "http://localhost:8080/raw/",
+			Download: "http://localhost:8080/",
+			Git:      "http://localhost:8080/",
+		}},
+	} {
+		if got := EndpointsForHost(tc.host); got != tc.want {
+			t.Errorf("EndpointsForHost(%q) = %+v, want %+v", tc.host, got, tc.want)
+		}
+	}
+}
+
+func TestHostTokens(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &GitHubApp{
+		AppID:          1,
+		InstallationID: 2,
+		PrivateKey:     pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+	}
+	for name, tc := range map[string]struct {
+		token string
+		app   *GitHubApp
+		want  string
+	}{
+		"token": {token: "test-token", want: "Bearer test-token"},
+		"app":   {app: app, want: "Bearer installation-token"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var (
+				mu   sync.Mutex
+				auth = map[string][]string{}
+			)
+			record := func(name string) *httptest.Server {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					if strings.HasSuffix(req.URL.Path, "/access_tokens") {
+						writeJSON(w, http.StatusCreated, map[string]any{"token": "installation-token", "expires_at": time.Now().Add(time.Hour)})
+						return
+					}
+					mu.Lock()
+					auth[name] = append(auth[name], req.Header.Get("Authorization"))
+					mu.Unlock()
+					http.NotFound(w, req)
+				}))
+				t.Cleanup(server.Close)
+				return server
+			}
+			destination, enterprise := record("destination"), record("enterprise")
+
+			r, err := New(Options{
+				Config: Configuration{
+					DstCfg: DstConfiguration{Owner: "community", Repo: "charts", Host: destination.URL},
+					SrcCfg: []SrcConfiguration{
+						{Name: "gardener", Repo: "gardener/gardener", Host: destination.URL},
+						{Name: "enterprise", Repo: "platform/gardener", Host: enterprise.URL},
+					},
+				},
+				CacheDir: t.TempDir(),
+				WorkDir:  t.TempDir(),
+				Token:    tc.token,
+				App:      tc.app,
+				Logger:   testLogger(t),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, cfg := range r.Config().SrcCfg {
+				if _, err := r.LatestVersion(context.Background(), cfg); err == nil {
+					t.Errorf("%s: expected the latest release to be missing", cfg.Name)
+				}
+			}
+
+			if len(auth["destination"]) == 0 || auth["destination"][0] != tc.want {
+				t.Errorf("expected %q to be sent to the destination host, got %q", tc.want, auth["destination"])
+			}
+			for _, header := range auth["enterprise"] {
+				if header != "" {
+					t.Errorf("expected anonymous requests to another host, got %q", header)
+				}
+			}
+			if len(auth["enterprise"]) == 0 {
+				t.Error("no request reached the other host")
+			}
+		})
+	}
+}
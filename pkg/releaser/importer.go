@@ -2,126 +2,83 @@ package releaser
 
 import (
 	"context"
-	"os"
+	"fmt"
 	"os/exec"
-	"regexp"
+	"path/filepath"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 
-	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/plumbing"
-	"github.com/google/go-github/v36/github"
-	"github.com/sirupsen/logrus"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
 )
 
-func importChart(cfg SrcConfiguration, src string) (chart.Chart, error) {
+func (r *Releaser) importChart(ctx context.Context, cfg SrcConfiguration, src string) (chart.Chart, error) {
 
-	// I did not find any package handling the symlinks to directories,
-	// so that the directories are copied over
-	// Therefore, just use a system command here
-	tempDir := "tmp"
-	tempRepoDir := "/tmp/" + cfg.Repo + "/"
-	logrus.Info("Git clone or pull: ", cfg.Repo, " Version: ", cfg.Version, " tmp-dir: ", tempRepoDir)
-
-	_, err := exec.Command("rm", "-rf", tempDir).Output()
+	repoDir := r.cacheDir(cfg)
+	unlock := r.lockRepo(repoDir)
+	defer unlock()
+	r.log.Info("Git clone or fetch: ", cfg.Repo, " Version: ", cfg.Version, " dir: ", repoDir)
+	h := r.sourceHost(cfg)
+	gitCtx, cancel := r.withGitTimeout(ctx)
+	_, err := r.opts.Git.Checkout(gitCtx, h.cloneURL(cfg.Repo), repoDir, cfg.Version, h.creds)
+	cancel()
 	if err != nil {
-		logrus.Warn(err)
-	}
-
-	// Clone the repository or open it, if it already exists on disk
-	// It is handeled like this for performance reasons, when e.g. exporting the charts
-	repo, err := git.PlainClone(tempRepoDir, false, &git.CloneOptions{
-		URL: "https://github.com/" + cfg.Repo,
-	})
-	if err == git.ErrRepositoryAlreadyExists {
-		repo, err = git.PlainOpen(tempRepoDir)
-	} else if err != nil {
-		logrus.Info(err)
+		return chart.Chart{}, fmt.Errorf("checking out %s %s: %w", cfg.Repo, cfg.Version, err)
 	}
 
-	// We want to checkout the "default" branch of our repo, so that a clean state is reached and we can pull
-	// Therefore, find out if the "default" branch is called "master" oder "main"
-	b, err := repo.Branches()
-	var branch *plumbing.Reference
-	for {
-		branch, err = b.Next()
-		logrus.Debug(branch.String())
-		if err != nil {
-			logrus.Error("I was not able to find a default branch, you should not rely on what I will do next")
-			break
-		}
-		if strings.Contains(string(branch.Name()), "master") || strings.Contains(string(branch.Name()), "main") {
-			break
-		}
-	}
-
-	// checkout the default branch now and pull
-	wt, err := repo.Worktree()
-
-	wt.Checkout(&git.CheckoutOptions{
-		Branch: branch.Name(),
-	})
+	tempDir, err := r.opts.FileSystem.MkdirTemp(r.opts.WorkDir, "chart-")
 	if err != nil {
-		logrus.Info(err)
-	}
-	err = wt.Reset(&git.ResetOptions{Mode: git.HardReset})
-	if err != nil {
-		logrus.Info(err)
-	}
-	err = wt.Pull(&git.PullOptions{})
-	if err != nil {
-		logrus.Info(err)
-	}
-
-	// checkout the target-tag after pull
-	err = wt.Checkout(&git.CheckoutOptions{
-		Branch: plumbing.NewTagReferenceName(cfg.Version),
-	})
-	if err != nil {
-		logrus.Warn(err)
-	}
-	err = wt.Reset(&git.ResetOptions{Mode: git.HardReset})
-	if err != nil {
-		logrus.Info(err)
+		return chart.Chart{}, err
 	}
+	defer r.opts.FileSystem.RemoveAll(tempDir)
+	chartDir := filepath.Join(tempDir, "chart")
 
-	_, err = exec.Command("cp", "-LR", tempRepoDir+src, tempDir).Output()
+	// I did not find any package handling the symlinks to directories,
+	// so that the directories are copied over
+	// Therefore, just use a system command here
+	out, err := exec.CommandContext(ctx, "cp", "-LR", filepath.Join(repoDir, src), chartDir).CombinedOutput()
 	if err != nil {
-		logrus.Warn(err)
+		return chart.Chart{}, fmt.Errorf("copying %s: %w: %s", src, err, strings.TrimSpace(string(out)))
 	}
 
-	resultChart, err := loader.Load(tempDir)
-	if err != nil {
-		logrus.Warn(err)
-		return chart.Chart{}, err
-	}
-	err = os.RemoveAll(tempDir)
+	resultChart, err := loader.Load(chartDir)
 	if err != nil {
-		logrus.Warn(err)
+		return chart.Chart{}, fmt.Errorf("loading chart %s: %w", src, err)
 	}
 	resultChart.Metadata.Version = cfg.Version
 
 	return *resultChart, nil
 }
 
-func ensureChart(c *chart.Chart, cfg SrcConfiguration) {
+// cacheDir returns the directory the repository of a source is cloned to.
+// Repositories of GitHub Enterprise hosts are kept in a directory per host.
+func (r *Releaser) cacheDir(cfg SrcConfiguration) string {
+	key := strings.NewReplacer("://", "/", ":", "_").Replace(hostKey(cfg.Host))
+	return filepath.Join(r.opts.CacheDir, key, cfg.Repo)
+}
+
+// chartVersion returns the version of the chart released for an upstream tag,
+// helm charts are versioned without the v prefix
+func chartVersion(tag string) string {
+	return strings.TrimPrefix(tag, "v")
+}
+
+func ensureChart(c *chart.Chart, cfg SrcConfiguration) error {
 
 	c.Metadata.APIVersion = "v2"
 
-	// helmcharts are versioned with strict semver (no v-Prefix)
-	re := regexp.MustCompile(`^v`)
-	c.Metadata.Version = string(re.ReplaceAll([]byte(cfg.Version), []byte("")))
+	c.Metadata.Version = chartVersion(cfg.Version)
 
 	valuesSerialized, err := yaml.Marshal(c.Values)
+	if err != nil {
+		return fmt.Errorf("serializing values of chart %s: %w", c.Name(), err)
+	}
 	valuesSerialized = []byte(strings.Replace(string(valuesSerialized), "tag: latest", "tag: "+cfg.Version, -1))
 	c.Values = map[string]any{}
-	yaml.Unmarshal(valuesSerialized, c.Values)
-
+	err = yaml.Unmarshal(valuesSerialized, c.Values)
 	if err != nil {
-		logrus.Error(err)
+		return fmt.Errorf("parsing values of chart %s: %w", c.Name(), err)
 	}
 	c.Raw = []*chart.File{{
 		Name: "values.yaml",
@@ -129,7 +86,7 @@ func ensureChart(c *chart.Chart, cfg SrcConfiguration) {
 	}}
 
 	if len(c.Dependencies()) == 0 {
-		return
+		return nil
 	}
 
 	for _, dep := range c.Dependencies() {
@@ -143,43 +100,39 @@ func ensureChart(c *chart.Chart, cfg SrcConfiguration) {
 		if c.Values == nil {
 			c.Values = make(map[string]interface{})
 		}
-		c.Values[dep.Name()] = map[string]bool{"enabled": false}
+		c.Values[dep.Name()] = map[string]any{"enabled": false}
 
 		valuesSerialized, err := yaml.Marshal(c.Values)
 		if err != nil {
-			logrus.Error(err)
+			return fmt.Errorf("serializing values of chart %s: %w", c.Name(), err)
 		}
 		c.Raw = []*chart.File{{
 			Name: "values.yaml",
 			Data: valuesSerialized,
 		}}
 
-		ensureChart(dep, cfg)
+		err = ensureChart(dep, cfg)
+		if err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func writeReleaseNotes(cfg SrcConfiguration, client *github.Client) *chart.File {
-	rr, _, err := client.Repositories.GetReleaseByTag(context.Background(), strings.Split(cfg.Repo, "/")[0], strings.Split(cfg.Repo, "/")[1], cfg.Version)
+func (r *Releaser) writeReleaseNotes(ctx context.Context, cfg SrcConfiguration) (*chart.File, error) {
+	rr, err := r.upstreamRelease(ctx, cfg)
 	if err != nil {
-		if strings.Contains(err.Error(), "Bad credentials") {
-			logrus.Error(err)
-			os.Exit(1)
-		}
-		logrus.Error(err)
-	}
-	if rr == nil {
-		logrus.Error("Error: Received empty GitHub Release for ", cfg.Repo)
-		os.Exit(1)
+		return nil, err
 	}
 
 	file := &chart.File{
 		Name: "RELEASE.md",
-		Data: []byte(*rr.Body),
+		Data: []byte(rr.GetBody()),
 	}
-	return file
+	return file, nil
 }
 
-func getTopLevelChart(cfg SrcConfiguration, client *github.Client) (chart.Chart, error) {
+func (r *Releaser) getTopLevelChart(ctx context.Context, cfg SrcConfiguration) (chart.Chart, error) {
 
 	var mainChart chart.Chart
 
@@ -209,19 +162,19 @@ func getTopLevelChart(cfg SrcConfiguration, client *github.Client) (chart.Chart,
 		for _, src := range cfg.Charts {
 			subChart := new(chart.Chart)
 			if src == "controller-registration" {
-				*subChart = generateExtensionChart(cfg)
+				*subChart, err = r.generateExtensionChart(ctx, cfg)
 			} else {
-				*subChart, err = importChart(cfg, src)
-				if err != nil {
-					continue
-				}
+				*subChart, err = r.importChart(ctx, cfg, src)
+			}
+			if err != nil {
+				return chart.Chart{}, err
 			}
 			mainChart.AddDependency(subChart)
 		}
 
 	} else {
 		// here we assume that the chart is already packaged appropriately by upstream
-		mainChart, err = importChart(cfg, cfg.Charts[0])
+		mainChart, err = r.importChart(ctx, cfg, cfg.Charts[0])
 		if err != nil {
 			return chart.Chart{}, err
 		}
@@ -229,7 +182,14 @@ func getTopLevelChart(cfg SrcConfiguration, client *github.Client) (chart.Chart,
 
 	// ensureChart makes sure that the chart dependencies are set correctly
 	mainChart.Metadata.Name = cfg.Name
-	mainChart.Files = append(mainChart.Files, writeReleaseNotes(cfg, client))
-	ensureChart(&mainChart, cfg)
+	releaseNotes, err := r.writeReleaseNotes(ctx, cfg)
+	if err != nil {
+		return chart.Chart{}, err
+	}
+	mainChart.Files = append(mainChart.Files, releaseNotes)
+	err = ensureChart(&mainChart, cfg)
+	if err != nil {
+		return chart.Chart{}, err
+	}
 	return mainChart, nil
 }
@@ -0,0 +1,281 @@
+package releaser
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/lint"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+// imageReference matches the images of rendered manifests
+var imageReference = regexp.MustCompile(`(?m)^\s*-?\s*image:\s*["']?([^\s"']+)`)
+
+// LintMessage is a finding of linting a chart, errors fail the chart
+type LintMessage struct {
+	Severity string `json:"severity"`
+	Path     string `json:"path"`
+	Message  string `json:"message"`
+}
+
+func (m LintMessage) String() string {
+	return fmt.Sprintf("[%s] %s: %s", m.Severity, m.Path, m.Message)
+}
+
+// LintResult is the result of linting the configured version of a source
+type LintResult struct {
+	Source   string        `json:"source"`
+	Version  string        `json:"version"`
+	Messages []LintMessage `json:"messages,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Lint builds the configured version of the given sources, or all sources if names is empty, and lints
+// the charts. Sources which could not be built or whose charts have lint errors are returned as Errors.
+func (r *Releaser) Lint(ctx context.Context, names []string) ([]*LintResult, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	results := make([]*LintResult, len(r.opts.Config.SrcCfg))
+	errs := make([]error, len(r.opts.Config.SrcCfg))
+	r.forEachSource(ctx, func(i int, cfg SrcConfiguration) {
+		if len(names) > 0 && !contains(names, cfg.Name) {
+			return
+		}
+		results[i] = &LintResult{Source: cfg.Name, Version: cfg.Version}
+		c, err := r.getTopLevelChart(ctx, cfg)
+		if err == nil {
+			results[i].Messages, err = r.lintChart(&c)
+		}
+		if err == nil {
+			err = lintErrors(results[i].Messages)
+		}
+		if err != nil {
+			results[i].Error = err.Error()
+			errs[i] = &SourceError{Source: cfg.Name, Version: cfg.Version, Err: err}
+		}
+	})
+
+	var linted []*LintResult
+	for _, result := range results {
+		if result != nil {
+			linted = append(linted, result)
+		}
+	}
+	var sourceErrs Errors
+	for _, err := range errs {
+		if err != nil {
+			sourceErrs = append(sourceErrs, err)
+		}
+	}
+	if ctx.Err() != nil {
+		return linted, fmt.Errorf("lint interrupted: %w", ctx.Err())
+	}
+	return linted, sourceErrs.ErrorOrNil()
+}
+
+// lintErrors summarizes the errors of lint messages, it returns nil if there are none
+func lintErrors(messages []LintMessage) error {
+	var errs []string
+	for _, m := range messages {
+		if m.Severity == LintError {
+			errs = append(errs, m.String())
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("lint failed: %s", strings.Join(errs, "; "))
+}
+
+// lintChart runs the helm linters on a chart and renders it with the default values and with all
+// dependencies enabled. Render errors, images tagged latest and empty templates are reported as well.
+// The chart is saved to a temporary directory, so that the checks see the chart as it is packaged.
+func (r *Releaser) lintChart(c *chart.Chart) ([]LintMessage, error) {
+	dir, err := r.opts.FileSystem.MkdirTemp(r.opts.WorkDir, "lint-")
+	if err != nil {
+		return nil, err
+	}
+	defer r.opts.FileSystem.RemoveAll(dir)
+	if err := chartutil.SaveDir(c, dir); err != nil {
+		return nil, err
+	}
+	chartDir := filepath.Join(dir, c.Name())
+
+	var messages []LintMessage
+	for _, m := range lint.All(chartDir, nil, "default", false).Messages {
+		// the charts of extensions only wrap their subcharts
+		if m.Path == "templates/" && len(c.Templates) == 0 && len(c.Dependencies()) > 0 {
+			continue
+		}
+		switch m.Severity {
+		case support.ErrorSev:
+			messages = append(messages, LintMessage{Severity: LintError, Path: m.Path, Message: m.Err.Error()})
+		case support.WarningSev:
+			messages = append(messages, LintMessage{Severity: LintWarning, Path: m.Path, Message: m.Err.Error()})
+		}
+	}
+
+	loaded, err := loader.Load(chartDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, image := range chartImages(loaded) {
+		if strings.HasSuffix(image, ":latest") {
+			messages = append(messages, LintMessage{Severity: LintError, Path: "values.yaml", Message: "image " + image + " is tagged latest"})
+		}
+	}
+
+	// templates which render to nothing with both values are suspicious, but may be disabled on purpose
+	rendered := map[string]bool{}
+	for _, variant := range []struct {
+		name   string
+		values func(*chart.Chart) map[string]any
+	}{
+		{"default values", func(*chart.Chart) map[string]any { return map[string]any{} }},
+		{"all dependencies enabled", allDependenciesEnabled},
+	} {
+		// rendering processes the dependencies of the chart, which modifies it
+		loaded, err := loader.Load(chartDir)
+		if err != nil {
+			return nil, err
+		}
+		manifests, err := renderWithDependencies(loaded, variant.values(loaded))
+		if err != nil {
+			messages = append(messages, LintMessage{Severity: LintError, Path: "templates", Message: "rendering with " + variant.name + ": " + err.Error()})
+			continue
+		}
+		for name, manifest := range manifests {
+			if !isManifest(name) {
+				continue
+			}
+			if strings.TrimSpace(stripComments(manifest)) != "" {
+				rendered[name] = true
+			}
+			for _, m := range imageReference.FindAllStringSubmatch(manifest, -1) {
+				if strings.HasSuffix(m[1], ":latest") {
+					messages = append(messages, LintMessage{Severity: LintError, Path: name, Message: "image " + m[1] + " is tagged latest"})
+				}
+			}
+		}
+	}
+
+	for _, t := range chartTemplates(loaded, c.Name()) {
+		if !isManifest(t.name) {
+			continue
+		}
+		switch {
+		case strings.TrimSpace(string(t.file.Data)) == "":
+			messages = append(messages, LintMessage{Severity: LintError, Path: t.name, Message: "template is empty"})
+		case !rendered[t.name]:
+			messages = append(messages, LintMessage{Severity: LintWarning, Path: t.name, Message: "template renders to nothing"})
+		}
+	}
+	return dedupLintMessages(messages), nil
+}
+
+// renderWithDependencies renders a chart like helm install does, i.e. disabled dependencies are not rendered
+func renderWithDependencies(c *chart.Chart, values map[string]any) (map[string]string, error) {
+	if err := chartutil.ProcessDependencies(c, values); err != nil {
+		return nil, err
+	}
+	renderValues, err := chartutil.ToRenderValues(c, values,
+		chartutil.ReleaseOptions{Name: c.Name(), Namespace: "default", IsInstall: true}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return engine.Render(c, renderValues)
+}
+
+// allDependenciesEnabled returns values which fulfill the conditions of all dependencies of a chart and its subcharts
+func allDependenciesEnabled(c *chart.Chart) map[string]any {
+	values := map[string]any{}
+	var walk func(c *chart.Chart, prefix string)
+	walk = func(c *chart.Chart, prefix string) {
+		for _, dep := range c.Metadata.Dependencies {
+			for _, condition := range strings.Split(dep.Condition, ",") {
+				if condition = strings.TrimSpace(condition); condition != "" {
+					setPath(values, prefix+condition, true)
+				}
+			}
+		}
+		for _, sub := range c.Dependencies() {
+			walk(sub, prefix+sub.Name()+".")
+		}
+	}
+	walk(c, "")
+	return values
+}
+
+// setPath sets the value at a dotted path, creating the maps on the way
+func setPath(values map[string]any, p string, v any) {
+	keys := strings.Split(p, ".")
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := values[k].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			values[k] = next
+		}
+		values = next
+	}
+	values[keys[len(keys)-1]] = v
+}
+
+type chartTemplate struct {
+	name string
+	file *chart.File
+}
+
+// chartTemplates returns the templates of a chart and its subcharts with the names the engine renders them as
+func chartTemplates(c *chart.Chart, prefix string) []chartTemplate {
+	var templates []chartTemplate
+	for _, t := range c.Templates {
+		templates = append(templates, chartTemplate{name: path.Join(prefix, t.Name), file: t})
+	}
+	for _, sub := range c.Dependencies() {
+		templates = append(templates, chartTemplates(sub, path.Join(prefix, "charts", sub.Name()))...)
+	}
+	return templates
+}
+
+// isManifest reports whether a template renders a manifest, partials and NOTES.txt do not
+func isManifest(name string) bool {
+	ext := path.Ext(name)
+	return !strings.HasPrefix(path.Base(name), "_") && (ext == ".yaml" || ext == ".yml" || ext == ".json")
+}
+
+func stripComments(manifest string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(manifest, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "---" && !strings.HasPrefix(trimmed, "#") {
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func dedupLintMessages(messages []LintMessage) []LintMessage {
+	seen := map[LintMessage]bool{}
+	var unique []LintMessage
+	for _, m := range messages {
+		if !seen[m] {
+			seen[m] = true
+			unique = append(unique, m)
+		}
+	}
+	return unique
+}
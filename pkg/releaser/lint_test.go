@@ -0,0 +1,73 @@
+package releaser
+
+import (
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func TestLintChart(t *testing.T) {
+	r, err := New(Options{WorkDir: t.TempDir(), CacheDir: t.TempDir(), Logger: testLogger(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: "v2", Name: "controller", Version: "0.1.0"},
+		Templates: []*chart.File{
+			{Name: "templates/deployment.yaml", Data: []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: controller
+spec:
+  template:
+    spec:
+      containers:
+      - image: {{ required "image is required" .Values.image }}
+`)},
+		},
+	}
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: "v2", Name: "provider-foo", Version: "0.1.0", Dependencies: []*chart.Dependency{
+			{Name: "controller", Condition: "controller.enabled"},
+		}},
+		Values: map[string]any{"controller": map[string]any{"enabled": false}},
+		Raw:    []*chart.File{{Name: "values.yaml", Data: []byte("controller:\n  enabled: false\n")}},
+		Templates: []*chart.File{
+			{Name: "templates/empty.yaml", Data: []byte("\n")},
+			{Name: "templates/pod.yaml", Data: []byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: foo
+spec:
+  containers:
+  - image: eu.gcr.io/gardener-project/foo:latest
+`)},
+		},
+	}
+	c.AddDependency(sub)
+
+	messages, err := r.lintChart(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errs []string
+	for _, m := range messages {
+		if m.Severity == LintError {
+			errs = append(errs, m.Path+": "+m.Message)
+		}
+	}
+	for _, want := range []string{
+		"provider-foo/templates/empty.yaml: template is empty",
+		"provider-foo/templates/pod.yaml: image eu.gcr.io/gardener-project/foo:latest is tagged latest",
+	} {
+		if !contains(errs, want) {
+			t.Errorf("expected lint error %q, got %v", want, errs)
+		}
+	}
+	// the controller is only rendered with all dependencies enabled
+	if joined := strings.Join(errs, "\n"); !strings.Contains(joined, "rendering with all dependencies enabled") ||
+		strings.Contains(joined, "rendering with default values") {
+		t.Errorf("expected a render error only with all dependencies enabled, got %v", errs)
+	}
+}
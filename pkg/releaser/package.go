@@ -0,0 +1,129 @@
+package releaser
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"sigs.k8s.io/yaml"
+)
+
+// helmGzipHeader is the extra gzip header helm writes into chart packages
+var helmGzipHeader = []byte("+aHR0cHM6Ly95b3V0dS5iZS96OVV6MWljandyTQo=")
+
+// packageEpoch is the modification time of the files of a package if the upstream commit date is unknown
+var packageEpoch = time.Unix(0, 0).UTC()
+
+// savePackage writes a chart package like chartutil.Save, but reproducibly: the entries are sorted by path and
+// all of them carry modTime and the same owner and permissions, so building the same chart twice yields
+// byte-identical packages. Chart.yaml is stable as it is marshaled with sorted keys. values.yaml is copied
+// from c.Raw, so it is only stable if the importer writes it deterministically: ensureChart marshals the
+// values with sorted keys, other files are taken verbatim from the upstream commit.
+// A zero modTime or one before packageEpoch is replaced by packageEpoch.
+//
+// The package only depends on the chart, which is not fully determined by the upstream commit though:
+// RELEASE.md is taken from the upstream release, whose notes can be edited, and the breaking changes
+// annotation depends on the versions published before.
+func (r *Releaser) savePackage(c *chart.Chart, dir string, modTime time.Time) (string, error) {
+	if err := c.Validate(); err != nil {
+		return "", fmt.Errorf("chart validation: %w", err)
+	}
+	files := make(map[string][]byte)
+	if err := packageFiles(files, c, ""); err != nil {
+		return "", err
+	}
+
+	if modTime.Before(packageEpoch) {
+		modTime = packageEpoch
+	}
+
+	var buf bytes.Buffer
+	zipper := gzip.NewWriter(&buf)
+	zipper.Header.Extra = helmGzipHeader
+	zipper.Header.Comment = "Helm"
+	tw := tar.NewWriter(zipper)
+	for _, name := range sortedKeys(files) {
+		h := &tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(files[name])),
+			ModTime: modTime.UTC().Truncate(time.Second),
+			// PAX supports long and non-ASCII paths, its records are deterministic for these headers
+			Format: tar.FormatPAX,
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			return "", err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return "", err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return "", err
+	}
+	if err := zipper.Close(); err != nil {
+		return "", err
+	}
+
+	if err := r.opts.FileSystem.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	packagePath := filepath.Join(dir, fmt.Sprintf("%s-%s.tgz", c.Name(), c.Metadata.Version))
+	return packagePath, r.opts.FileSystem.WriteFile(packagePath, buf.Bytes(), 0644)
+}
+
+// packageFiles collects the files of a chart and its dependencies by their path in the package,
+// following the layout of chartutil.Save
+func packageFiles(files map[string][]byte, c *chart.Chart, prefix string) error {
+	base := path.Join(prefix, c.Name())
+
+	md := *c.Metadata
+	if md.APIVersion == chart.APIVersionV1 {
+		md.Dependencies = nil
+	}
+	data, err := yaml.Marshal(&md)
+	if err != nil {
+		return err
+	}
+	files[path.Join(base, chartutil.ChartfileName)] = data
+
+	if md.APIVersion == chart.APIVersionV2 && c.Lock != nil {
+		data, err := yaml.Marshal(c.Lock)
+		if err != nil {
+			return err
+		}
+		files[path.Join(base, "Chart.lock")] = data
+	}
+	for _, f := range c.Raw {
+		if f.Name == chartutil.ValuesfileName {
+			files[path.Join(base, chartutil.ValuesfileName)] = f.Data
+		}
+	}
+	if c.Schema != nil {
+		if !json.Valid(c.Schema) {
+			return errors.New("invalid JSON in " + chartutil.SchemafileName)
+		}
+		files[path.Join(base, chartutil.SchemafileName)] = c.Schema
+	}
+	for _, f := range c.Templates {
+		files[path.Join(base, filepath.ToSlash(f.Name))] = f.Data
+	}
+	for _, f := range c.Files {
+		files[path.Join(base, filepath.ToSlash(f.Name))] = f.Data
+	}
+
+	for _, dep := range c.Dependencies() {
+		if err := packageFiles(files, dep, path.Join(base, chartutil.ChartsDir)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
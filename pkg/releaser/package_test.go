@@ -0,0 +1,104 @@
+package releaser
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+func TestSavePackage(t *testing.T) {
+	r, err := New(Options{WorkDir: t.TempDir(), CacheDir: t.TempDir(), Logger: testLogger(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitDate := time.Date(2022, 8, 8, 23, 6, 40, 0, time.UTC)
+	cfg := SrcConfiguration{Name: "provider-foo", Version: "v0.1.0"}
+	// long and non-ASCII paths do not fit into USTAR headers
+	longName := "templates/" + strings.Repeat("very-long-name-", 10) + "deployment.yaml"
+
+	build := func(modTime time.Time) []byte {
+		sub := &chart.Chart{
+			Metadata: &chart.Metadata{APIVersion: "v2", Name: "controller", Version: "0.1.0"},
+			Values:   map[string]any{"image": map[string]any{"tag": "latest", "repository": "foo"}, "replicas": 1, "a": true},
+			Templates: []*chart.File{
+				{Name: "templates/b.yaml", Data: []byte("b")},
+				{Name: "templates/a.yaml", Data: []byte("a")},
+				{Name: longName, Data: []byte("long")},
+				{Name: "templates/größe.yaml", Data: []byte("size")},
+			},
+		}
+		c := &chart.Chart{Metadata: &chart.Metadata{Name: cfg.Name}}
+		c.AddDependency(sub)
+		if err := ensureChart(c, cfg); err != nil {
+			t.Fatal(err)
+		}
+		path, err := r.savePackage(c, t.TempDir(), modTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	first := build(commitDate)
+	if !bytes.Equal(first, build(commitDate)) {
+		t.Fatal("building the same chart twice yields different packages")
+	}
+	if bytes.Equal(first, build(commitDate.Add(time.Hour))) {
+		t.Error("the modification time is not taken from the commit date")
+	}
+
+	c, err := loader.LoadArchive(bytes.NewReader(first))
+	if err != nil {
+		t.Fatalf("loading package: %v", err)
+	}
+	if len(c.Dependencies()) != 1 || c.Dependencies()[0].Values["image"].(map[string]any)["tag"] != "v0.1.0" {
+		t.Errorf("unexpected package contents: %+v", c.Dependencies())
+	}
+	if len(c.Dependencies()[0].Templates) != 4 {
+		t.Errorf("expected 4 templates in the subchart, got %d", len(c.Dependencies()[0].Templates))
+	}
+	names := packageHeaders(t, first, commitDate)
+	if !sort.StringsAreSorted(names) {
+		t.Errorf("entries are not sorted: %v", names)
+	}
+
+	// an unknown commit date falls back to the epoch
+	packageHeaders(t, build(time.Time{}), packageEpoch)
+}
+
+// packageHeaders checks the headers of a package and returns the names of its entries
+func packageHeaders(t *testing.T, data []byte, modTime time.Time) []string {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(zr)
+	var names []string
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, h.Name)
+		if !h.ModTime.Equal(modTime) || h.Mode != 0644 || h.Uid != 0 || h.Gid != 0 {
+			t.Errorf("%s: unexpected header %+v", h.Name, h)
+		}
+	}
+	return names
+}
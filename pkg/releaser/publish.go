@@ -0,0 +1,211 @@
+package releaser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/go-github/v36/github"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/yaml"
+)
+
+// publish creates a release for each package and pushes the updated index to the pages branch.
+// It returns the URL of the pull request, if index updates are proposed as pull requests.
+func (r *Releaser) publish(ctx context.Context, destRepo string, index *repo.IndexFile, packages []*chartPackage) (string, error) {
+	r.log.Info("Creating releases")
+	var added []string
+	for _, pkg := range packages {
+		if err := ctx.Err(); err != nil {
+			return "", fmt.Errorf("publishing interrupted, run the reconcile command to add the created releases to the index: %w", err)
+		}
+		released, err := r.createRelease(ctx, pkg)
+		if err != nil {
+			return "", fmt.Errorf("creating release for %s: %w", filepath.Base(pkg.path), err)
+		}
+		if index.Has(pkg.chart.Name(), pkg.chart.Metadata.Version) {
+			r.log.Info("Index already contains ", pkg.chart.Name(), "-", pkg.chart.Metadata.Version)
+			continue
+		}
+		addToIndex(index, released.metadata, released.url, released.digest, time.Now())
+		added = append(added, pkg.chart.Name()+"-"+pkg.chart.Metadata.Version)
+	}
+
+	r.log.Info("Updating index")
+	var pullRequestURL string
+	err := r.writeIndex(filepath.Join(destRepo, "index.yaml"), index)
+	if err == nil {
+		pullRequestURL, err = r.pushIndex(ctx, destRepo, newIndexUpdate("update", added), "Update index.yaml")
+	}
+	if err != nil {
+		return "", fmt.Errorf("updating index, run the reconcile command to repair it: %w", err)
+	}
+	return pullRequestURL, nil
+}
+
+// releasedPackage is a chart package attached to a release of the destination repository
+type releasedPackage struct {
+	url      string
+	metadata *chart.Metadata
+	digest   string
+}
+
+// createRelease creates the release of a chart package in the destination repository and returns
+// the uploaded package. The provenance file is uploaded next to the package. Existing releases
+// are reused, only a missing provenance file is added to them. As the package of an existing release
+// may differ from the package built in this run, it is downloaded and returned instead.
+func (r *Releaser) createRelease(ctx context.Context, pkg *chartPackage) (*releasedPackage, error) {
+	dst := r.opts.Config.DstCfg
+	gh := r.host(dst.Host).github
+	tag := pkg.chart.Name() + "-" + pkg.chart.Metadata.Version
+	assetName := filepath.Base(pkg.path)
+
+	release, resp, err := gh.GetReleaseByTag(ctx, dst.Owner, dst.Repo, tag)
+	if err == nil {
+		r.log.Info("Release ", tag, " already exists")
+		pkg.report.ReleaseURL = release.GetHTMLURL()
+		var asset *github.ReleaseAsset
+		hasProv := false
+		for _, a := range release.Assets {
+			switch a.GetName() {
+			case assetName:
+				asset = a
+			case assetName + ".prov":
+				hasProv = true
+			}
+		}
+		if asset == nil {
+			return nil, fmt.Errorf("release %s exists, but has no asset %s", tag, assetName)
+		}
+		md, digest, err := r.inspectPackage(ctx, asset.GetBrowserDownloadURL())
+		if err != nil {
+			return nil, fmt.Errorf("inspecting existing asset %s: %w", assetName, err)
+		}
+		matches := digest == pkg.report.Digest
+		if !matches {
+			r.log.Warn("Existing asset ", assetName, " differs from the package built in this run, keeping the published package")
+		}
+		if pkg.provPath != "" && !hasProv {
+			// the provenance file was signed for the package of this run and cannot verify another one
+			if !matches {
+				r.log.Warn("Not uploading provenance file to release ", tag, " as it does not match the existing asset")
+			} else if _, err := r.uploadAsset(ctx, release.GetID(), pkg.provPath); err != nil {
+				return nil, err
+			}
+		}
+		return &releasedPackage{url: asset.GetBrowserDownloadURL(), metadata: md, digest: digest}, nil
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		return nil, err
+	}
+
+	release, _, err = gh.CreateRelease(ctx, dst.Owner, dst.Repo, &github.RepositoryRelease{
+		Name:    github.String(tag),
+		TagName: github.String(tag),
+		Body:    github.String(pkg.notes),
+	})
+	if err != nil {
+		return nil, err
+	}
+	pkg.report.ReleaseURL = release.GetHTMLURL()
+
+	asset, err := r.uploadAsset(ctx, release.GetID(), pkg.path)
+	if err != nil {
+		return nil, err
+	}
+	if pkg.provPath != "" {
+		if _, err := r.uploadAsset(ctx, release.GetID(), pkg.provPath); err != nil {
+			return nil, err
+		}
+	}
+	return &releasedPackage{url: asset.GetBrowserDownloadURL(), metadata: pkg.chart.Metadata, digest: pkg.report.Digest}, nil
+}
+
+// uploadAsset attaches a file to a release of the destination repository
+func (r *Releaser) uploadAsset(ctx context.Context, releaseID int64, path string) (*github.ReleaseAsset, error) {
+	dst := r.opts.Config.DstCfg
+	name := filepath.Base(path)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	asset, _, err := r.host(dst.Host).github.UploadReleaseAsset(ctx, dst.Owner, dst.Repo, releaseID, &github.UploadOptions{Name: name}, f)
+	if err != nil {
+		return nil, fmt.Errorf("uploading %s: %w", name, err)
+	}
+	return asset, nil
+}
+
+// releaseNotes returns the content of RELEASE.md of the chart, or its description if there is none
+func releaseNotes(c *chart.Chart) string {
+	for _, f := range c.Files {
+		if f.Name == "RELEASE.md" {
+			return string(f.Data)
+		}
+	}
+	return c.Metadata.Description
+}
+
+// cloneDestinationRepo clones the pages branch of the destination repository into dir
+func (r *Releaser) cloneDestinationRepo(ctx context.Context, dir string) error {
+	dst := r.opts.Config.DstCfg
+	r.log.Info("Cloning destrepo ", dst.Owner, "/", dst.Repo)
+	ctx, cancel := r.withGitTimeout(ctx)
+	defer cancel()
+	return r.opts.Git.Clone(ctx, r.host(dst.Host).endpoints.cloneURL(dst.Owner+"/"+dst.Repo), dir, pagesBranch)
+}
+
+// commitAndPush commits the index of the clone of the destination repository and pushes it
+func (r *Releaser) commitAndPush(ctx context.Context, destRepo string, message string) error {
+	ctx, cancel := r.withGitTimeout(ctx)
+	defer cancel()
+	return r.opts.Git.CommitAndPush(ctx, destRepo, message, "index.yaml")
+}
+
+// loadIndex reads an index.yaml, a missing file results in an empty index
+func (r *Releaser) loadIndex(path string) (*repo.IndexFile, error) {
+	data, err := r.opts.FileSystem.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		r.log.Warn("No index.yaml found in destination repository, creating a new one")
+		return repo.NewIndexFile(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	index := &repo.IndexFile{}
+	err = yaml.Unmarshal(data, index)
+	if err != nil {
+		return nil, err
+	}
+	if index.Entries == nil {
+		index.Entries = map[string]repo.ChartVersions{}
+	}
+	return index, nil
+}
+
+// writeIndex sorts the entries of the index and writes it to path
+func (r *Releaser) writeIndex(path string, index *repo.IndexFile) error {
+	index.SortEntries()
+	index.Generated = time.Now()
+	data, err := yaml.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return r.opts.FileSystem.WriteFile(path, data, 0644)
+}
+
+// addToIndex adds a chart package, which can be downloaded from url, to the index
+func addToIndex(index *repo.IndexFile, md *chart.Metadata, url string, digest string, created time.Time) {
+	index.Entries[md.Name] = append(index.Entries[md.Name], &repo.ChartVersion{
+		Metadata: md,
+		URLs:     []string{url},
+		Digest:   digest,
+		Created:  created,
+	})
+}
@@ -0,0 +1,191 @@
+package releaser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/go-github/v36/github"
+)
+
+const defaultPullRequestBranch = "chart-releaser/{{ .Command }}-{{ .Timestamp }}"
+
+// indexMarker marks the chart versions an index pull request adds in its body
+var indexMarker = regexp.MustCompile(`<!-- chart-releaser-index: (\S+) -->`)
+
+// IndexUpdate describes a change of the index of the destination repository. It is the
+// data of the branch and commit message templates of pull requests.
+type IndexUpdate struct {
+	// Command is the command which changed the index, i.e. "update" or "reconcile"
+	Command string
+	// Charts are the chart versions added to the index, e.g. "gardener-controlplane-1.53.0"
+	Charts []string
+	// Date is the date of the change as YYYY-MM-DD
+	Date string
+	// Timestamp is the time of the change in seconds since the epoch
+	Timestamp int64
+}
+
+func newIndexUpdate(command string, charts []string) IndexUpdate {
+	now := time.Now()
+	return IndexUpdate{
+		Command:   command,
+		Charts:    charts,
+		Date:      now.Format("2006-01-02"),
+		Timestamp: now.Unix(),
+	}
+}
+
+// pushIndex commits the index of the clone of the destination repository and pushes it to the pages branch.
+// If pull requests are configured, it is pushed to a new branch instead and the URL of the pull request is returned.
+func (r *Releaser) pushIndex(ctx context.Context, destRepo string, update IndexUpdate, message string) (string, error) {
+	cfg := r.opts.Config.DstCfg.PullRequest
+	if cfg == nil {
+		return "", r.commitAndPush(ctx, destRepo, message)
+	}
+
+	branchTemplate := cfg.Branch
+	if branchTemplate == "" {
+		branchTemplate = defaultPullRequestBranch
+	}
+	branch, err := renderTemplate("branch", branchTemplate, update)
+	if err != nil {
+		return "", err
+	}
+	if cfg.CommitMessage != "" {
+		message, err = renderTemplate("commit message", cfg.CommitMessage, update)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	gitCtx, cancel := r.withGitTimeout(ctx)
+	err = r.opts.Git.Branch(gitCtx, destRepo, branch)
+	cancel()
+	if err != nil {
+		return "", fmt.Errorf("creating branch %s: %w", branch, err)
+	}
+	if err := r.commitAndPush(ctx, destRepo, message); err != nil {
+		return "", err
+	}
+
+	dst := r.opts.Config.DstCfg
+	pulls := r.host(dst.Host).pulls
+	title, body := splitCommitMessage(message)
+	if body == "" {
+		body = pullRequestBody(update)
+	}
+	for _, c := range update.Charts {
+		body += fmt.Sprintf("\n<!-- chart-releaser-index: %s -->", c)
+	}
+	pull, _, err := pulls.Create(ctx, dst.Owner, dst.Repo, &github.NewPullRequest{
+		Title: github.String(title),
+		Head:  github.String(branch),
+		Base:  github.String(pagesBranch),
+		Body:  github.String(body),
+	})
+	if err != nil {
+		return "", fmt.Errorf("opening pull request for branch %s: %w", branch, err)
+	}
+	r.log.Info("Opened pull request ", pull.GetHTMLURL())
+	r.assignPullRequest(ctx, pulls, dst.Owner, dst.Repo, pull, cfg.Reviewers, cfg.Labels)
+	return pull.GetHTMLURL(), nil
+}
+
+// pendingIndexUpdates returns the URLs of the open index pull requests by the chart versions they add.
+// It returns nil if index updates are not proposed as pull requests.
+func (r *Releaser) pendingIndexUpdates(ctx context.Context) (map[string]string, error) {
+	dst := r.opts.Config.DstCfg
+	if dst.PullRequest == nil {
+		return nil, nil
+	}
+	pending := map[string]string{}
+	opts := &github.PullRequestListOptions{State: "open", Base: pagesBranch, ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		pulls, resp, err := r.host(dst.Host).pulls.List(ctx, dst.Owner, dst.Repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("listing pull requests of %s/%s: %w", dst.Owner, dst.Repo, err)
+		}
+		for _, pull := range pulls {
+			if pull.GetBase().GetRef() != pagesBranch {
+				continue
+			}
+			for _, m := range indexMarker.FindAllStringSubmatch(pull.GetBody(), -1) {
+				pending[m[1]] = pull.GetHTMLURL()
+			}
+		}
+		if resp == nil || resp.NextPage == 0 {
+			return pending, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// assignPullRequest requests reviewers and adds labels to a pull request. The change is
+// proposed already, so failing to assign it is not worth failing the run.
+func (r *Releaser) assignPullRequest(ctx context.Context, pulls PullRequests, owner string, repo string, pull *github.PullRequest, reviewers []string, labels []string) {
+	if len(reviewers) > 0 {
+		_, _, err := pulls.RequestReviewers(ctx, owner, repo, pull.GetNumber(), reviewersRequest(reviewers))
+		if err != nil {
+			r.log.Warn("Requesting reviewers of ", pull.GetHTMLURL(), " failed: ", err)
+		}
+	}
+	if len(labels) > 0 {
+		_, _, err := pulls.AddLabelsToIssue(ctx, owner, repo, pull.GetNumber(), labels)
+		if err != nil {
+			r.log.Warn("Labeling ", pull.GetHTMLURL(), " failed: ", err)
+		}
+	}
+}
+
+// renderTemplate renders a Go template of the configuration
+func renderTemplate(name string, text string, data any) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("parsing %s template: %w", name, err)
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("rendering %s template: %w", name, err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// splitCommitMessage splits a commit message into its first line and the rest
+func splitCommitMessage(message string) (string, string) {
+	parts := strings.SplitN(message, "\n", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], strings.TrimSpace(parts[1])
+}
+
+// pullRequestBody lists the chart versions added by an index update
+func pullRequestBody(update IndexUpdate) string {
+	if len(update.Charts) == 0 {
+		return "This pull request updates index.yaml."
+	}
+	var b strings.Builder
+	b.WriteString("This pull request adds the following chart versions to index.yaml:\n\n")
+	for _, c := range update.Charts {
+		fmt.Fprintf(&b, "- %s\n", c)
+	}
+	return b.String()
+}
+
+// reviewersRequest splits reviewers into users and teams, teams are given as "org/team"
+func reviewersRequest(reviewers []string) github.ReviewersRequest {
+	var req github.ReviewersRequest
+	for _, reviewer := range reviewers {
+		if _, team, ok := strings.Cut(reviewer, "/"); ok {
+			req.TeamReviewers = append(req.TeamReviewers, team)
+		} else {
+			req.Reviewers = append(req.Reviewers, reviewer)
+		}
+	}
+	return req
+}
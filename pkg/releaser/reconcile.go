@@ -0,0 +1,200 @@
+package releaser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-github/v36/github"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/provenance"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// ReconcileOptions controls how Reconcile repairs the index of the destination repository
+type ReconcileOptions struct {
+	// Rebuild discards the existing index.yaml and creates it from the release assets only
+	Rebuild bool
+	// Prune removes index entries whose release assets do not exist anymore
+	Prune bool
+	// DryRun only reports the differences without pushing a new index
+	DryRun bool
+}
+
+// ReconcileResult summarizes the differences between the release assets and the index
+type ReconcileResult struct {
+	Added  []string
+	Stale  []string
+	Pushed bool
+	// PullRequestURL is the pull request proposing the reconciled index, if pull requests are configured
+	PullRequestURL string
+}
+
+// Reconcile compares the chart packages attached to the releases of the destination
+// repository with its index.yaml. Missing entries are added, entries whose assets are gone
+// are reported (and removed, if requested) and the resulting index is pushed to the pages branch.
+func (r *Releaser) Reconcile(ctx context.Context, opts ReconcileOptions) (ReconcileResult, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	var result ReconcileResult
+	dst := r.opts.Config.DstCfg
+
+	destRepo, err := r.opts.FileSystem.MkdirTemp(r.opts.WorkDir, "destrepo-")
+	if err != nil {
+		return result, err
+	}
+	defer r.opts.FileSystem.RemoveAll(destRepo)
+
+	err = r.cloneDestinationRepo(ctx, destRepo)
+	if err != nil {
+		return result, fmt.Errorf("cloning destination repository: %w", err)
+	}
+	indexPath := filepath.Join(destRepo, "index.yaml")
+
+	index := repo.NewIndexFile()
+	if !opts.Rebuild {
+		index, err = r.loadIndex(indexPath)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	releases, err := listAllReleases(ctx, r.host(dst.Host).github, dst.Owner, dst.Repo)
+	if err != nil {
+		return result, fmt.Errorf("listing releases of destination repository: %w", err)
+	}
+
+	indexedURLs := make(map[string]bool)
+	for _, versions := range index.Entries {
+		for _, v := range versions {
+			for _, u := range v.URLs {
+				indexedURLs[u] = true
+			}
+		}
+	}
+
+	// add every chart package which is attached to a release but unknown to the index
+	assetURLs := make(map[string]bool)
+	for _, rel := range releases {
+		for _, asset := range rel.Assets {
+			if !strings.HasSuffix(asset.GetName(), ".tgz") {
+				continue
+			}
+			assetURL := asset.GetBrowserDownloadURL()
+			assetURLs[assetURL] = true
+			if indexedURLs[assetURL] {
+				continue
+			}
+
+			md, digest, err := r.inspectPackage(ctx, assetURL)
+			if err != nil {
+				return result, fmt.Errorf("adding %s to index: %w", asset.GetName(), err)
+			}
+			if index.Has(md.Name, md.Version) {
+				r.log.Warn("Index already contains ", md.Name, "-", md.Version, " with a different URL, skipping ", assetURL)
+				continue
+			}
+			r.log.Info("Adding missing index entry ", md.Name, "-", md.Version)
+			addToIndex(index, md, assetURL, digest, rel.GetCreatedAt().Time)
+			result.Added = append(result.Added, md.Name+"-"+md.Version)
+		}
+	}
+
+	// flag all entries which point to release assets of the destination repository
+	// that do not exist anymore
+	downloadPrefix := r.host(dst.Host).endpoints.releaseDownloadPrefix(dst.Owner + "/" + dst.Repo)
+	for name, versions := range index.Entries {
+		var kept repo.ChartVersions
+		for _, v := range versions {
+			stale := false
+			for _, u := range v.URLs {
+				if strings.HasPrefix(u, downloadPrefix) && !assetURLs[u] {
+					stale = true
+				}
+			}
+			if stale {
+				r.log.Warn("Index entry ", name, "-", v.Version, " refers to a release asset which does not exist anymore")
+				result.Stale = append(result.Stale, name+"-"+v.Version)
+				if opts.Prune {
+					continue
+				}
+			}
+			kept = append(kept, v)
+		}
+		if len(kept) == 0 {
+			delete(index.Entries, name)
+		} else {
+			index.Entries[name] = kept
+		}
+	}
+
+	changed := opts.Rebuild || len(result.Added) > 0 || (opts.Prune && len(result.Stale) > 0)
+	if !changed {
+		r.log.Info("Index is in sync with the release assets")
+		return result, nil
+	}
+	if opts.DryRun {
+		r.log.Info("Dry run, not pushing the reconciled index")
+		return result, nil
+	}
+
+	err = r.writeIndex(indexPath, index)
+	if err != nil {
+		return result, err
+	}
+	result.PullRequestURL, err = r.pushIndex(ctx, destRepo, newIndexUpdate("reconcile", result.Added), "Reconcile index.yaml with release assets")
+	if err != nil {
+		return result, fmt.Errorf("pushing index: %w", err)
+	}
+	result.Pushed = true
+
+	return result, nil
+}
+
+// listAllReleases pages through all releases of a repository
+func listAllReleases(ctx context.Context, gh GitHub, owner string, repo string) ([]*github.RepositoryRelease, error) {
+	var releases []*github.RepositoryRelease
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := gh.ListReleases(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, page...)
+		if resp == nil || resp.NextPage == 0 {
+			return releases, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// inspectPackage downloads a chart package and returns its metadata and digest
+func (r *Releaser) inspectPackage(ctx context.Context, url string) (*chart.Metadata, string, error) {
+	creds, err := r.assetCredentials(url)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := r.opts.Fetcher.Fetch(ctx, url, creds)
+	if err != nil {
+		return nil, "", err
+	}
+
+	c, err := loader.LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	digest, err := provenance.Digest(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	if c.Metadata.APIVersion == "" {
+		c.Metadata.APIVersion = chart.APIVersionV2
+	}
+	if err := c.Metadata.Validate(); err != nil {
+		return nil, "", err
+	}
+	return c.Metadata, digest, nil
+}
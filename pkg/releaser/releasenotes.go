@@ -0,0 +1,241 @@
+package releaser
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/google/go-github/v36/github"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"sigs.k8s.io/yaml"
+)
+
+// ReleaseNotesData is the data release notes templates are rendered with
+type ReleaseNotesData struct {
+	// Name is the name of the chart
+	Name string
+	// Version is the version of the chart
+	Version string
+	// Repo is the upstream repository, i.e. "owner/repo"
+	Repo string
+	// Tag is the upstream tag the chart was built from
+	Tag string
+	// Commit is the upstream commit the tag points to
+	Commit string
+	// Digest is the sha256 digest of the chart package
+	Digest string
+	// UpstreamNotes is the body of the upstream GitHub release
+	UpstreamNotes string
+	// UpstreamURL is the URL of the upstream GitHub release
+	UpstreamURL string
+	// Subcharts are the charts contained in the chart
+	Subcharts []SubchartRef
+	// Images are the container images referenced by the values of the chart and its subcharts
+	Images []string
+	// ValuesDiff compares the default values to the previous version, it is nil for the first version
+	ValuesDiff *ValuesDiff
+	// Compat classifies the changes compared to the previous version, it is nil for the first version
+	Compat *CompatReport
+}
+
+// SubchartRef is a chart contained in a released chart
+type SubchartRef struct {
+	Name    string
+	Version string
+}
+
+// loadReleaseNotesTemplates parses the release notes templates of the configuration by source name,
+// the default template of the configuration is stored under the empty name
+func loadReleaseNotesTemplates(config Configuration) (map[string]*template.Template, error) {
+	templates := map[string]*template.Template{}
+	load := func(key string, path string) error {
+		if path == "" {
+			return nil
+		}
+		text, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading release notes template: %w", err)
+		}
+		tmpl, err := template.New(path).Funcs(sprig.TxtFuncMap()).Option("missingkey=error").Parse(string(text))
+		if err != nil {
+			return fmt.Errorf("parsing release notes template: %w", err)
+		}
+		templates[key] = tmpl
+		return nil
+	}
+	if err := load("", config.ReleaseNotesTemplate); err != nil {
+		return nil, err
+	}
+	for _, cfg := range config.SrcCfg {
+		if err := load(cfg.Name, cfg.ReleaseNotesTemplate); err != nil {
+			return nil, fmt.Errorf("source %s: %w", cfg.Name, err)
+		}
+	}
+	return templates, nil
+}
+
+// renderReleaseNotes renders the release notes template of a source. Without a template,
+// the upstream notes (or the description of the chart) are used.
+func (r *Releaser) renderReleaseNotes(ctx context.Context, cfg SrcConfiguration, pkg *chartPackage) (string, error) {
+	tmpl, ok := r.notesTemplates[cfg.Name]
+	if !ok {
+		tmpl, ok = r.notesTemplates[""]
+	}
+	if !ok {
+		notes := releaseNotes(pkg.chart)
+		if compat := pkg.report.Compat; compat != nil && compat.BreakingInNonMajor() && r.compatMode(cfg) == CompatAnnotate {
+			notes = strings.TrimRight(notes, "\n") + "\n\n## Breaking changes\n\n" + compat.Markdown()
+		}
+		if diff := pkg.report.ValuesDiff; diff != nil && !diff.Empty() {
+			notes = strings.TrimRight(notes, "\n") + "\n\n## Values\n\n" + diff.Markdown()
+		}
+		return notes, nil
+	}
+
+	upstream, err := r.upstreamRelease(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+	data := ReleaseNotesData{
+		Name:          pkg.chart.Name(),
+		Version:       pkg.chart.Metadata.Version,
+		Repo:          cfg.Repo,
+		Tag:           cfg.Version,
+		Commit:        pkg.report.Commit,
+		Digest:        pkg.report.Digest,
+		UpstreamNotes: upstream.GetBody(),
+		UpstreamURL:   upstream.GetHTMLURL(),
+		Images:        chartImages(pkg.chart),
+		ValuesDiff:    pkg.report.ValuesDiff,
+		Compat:        pkg.report.Compat,
+	}
+	for _, dep := range pkg.chart.Dependencies() {
+		data.Subcharts = append(data.Subcharts, SubchartRef{Name: dep.Name(), Version: dep.Metadata.Version})
+	}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("rendering release notes: %w", err)
+	}
+	return b.String(), nil
+}
+
+// upstreamRelease returns the GitHub release of the version of a source
+func (r *Releaser) upstreamRelease(ctx context.Context, cfg SrcConfiguration) (*github.RepositoryRelease, error) {
+	owner, repo := splitRepo(cfg.Repo)
+	rr, _, err := r.sourceHost(cfg).github.GetReleaseByTag(ctx, owner, repo, cfg.Version)
+	if err != nil {
+		return nil, fmt.Errorf("fetching GitHub release %s of %s: %w", cfg.Version, cfg.Repo, err)
+	}
+	if rr == nil {
+		return nil, fmt.Errorf("received empty GitHub release %s of %s", cfg.Version, cfg.Repo)
+	}
+	return rr, nil
+}
+
+// chartImages returns the sorted container images referenced by the values of a chart and its subcharts,
+// including the charts embedded in ControllerDeployments of extensions.
+// Images are either maps with a repository and a tag, or strings in fields called image.
+func chartImages(c *chart.Chart) []string {
+	images := map[string]bool{}
+	var walk func(v any, key string)
+	walk = func(v any, key string) {
+		switch v := v.(type) {
+		case map[string]any:
+			if repository, ok := v["repository"].(string); ok && repository != "" {
+				image := repository
+				if tag, ok := v["tag"]; ok && fmt.Sprint(tag) != "" {
+					image += ":" + fmt.Sprint(tag)
+				}
+				images[image] = true
+			}
+			for k, child := range v {
+				walk(child, k)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child, key)
+			}
+		case string:
+			if key == "image" && v != "" && !strings.ContainsAny(v, " {") {
+				images[v] = true
+			}
+		}
+	}
+	var walkChart func(c *chart.Chart)
+	walkChart = func(c *chart.Chart) {
+		walk(map[string]any(c.Values), "")
+		for _, dep := range c.Dependencies() {
+			walkChart(dep)
+		}
+		for _, deployment := range controllerDeployments(c) {
+			walk(deployment.ProviderConfig.Values, "")
+			if embedded := deployment.chart(); embedded != nil {
+				walkChart(embedded)
+			}
+		}
+	}
+	walkChart(c)
+
+	result := make([]string, 0, len(images))
+	for image := range images {
+		result = append(result, image)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// controllerDeployment are the fields of a ControllerDeployment of type helm
+type controllerDeployment struct {
+	Kind           string `json:"kind"`
+	ProviderConfig struct {
+		// Chart is the base64 encoded chart package of the extension controller
+		Chart  string         `json:"chart"`
+		Values map[string]any `json:"values"`
+	} `json:"providerConfig"`
+}
+
+// chart returns the chart embedded in the ControllerDeployment, or nil if it cannot be loaded
+func (d *controllerDeployment) chart() *chart.Chart {
+	data, err := base64.StdEncoding.DecodeString(d.ProviderConfig.Chart)
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	c, err := loader.LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	return c
+}
+
+// controllerDeployments returns the ControllerDeployments in the templates of a chart. Template
+// directives, like the values added by generateExtensionChart, are skipped.
+func controllerDeployments(c *chart.Chart) []*controllerDeployment {
+	var deployments []*controllerDeployment
+	for _, t := range c.Templates {
+		for _, doc := range strings.Split(string(t.Data), "\n---") {
+			if !strings.Contains(doc, "ControllerDeployment") {
+				continue
+			}
+			var lines []string
+			for _, line := range strings.Split(doc, "\n") {
+				if !strings.HasPrefix(strings.TrimSpace(line), "{{") {
+					lines = append(lines, line)
+				}
+			}
+			d := &controllerDeployment{}
+			if err := yaml.Unmarshal([]byte(strings.Join(lines, "\n")), d); err != nil || d.Kind != "ControllerDeployment" {
+				continue
+			}
+			deployments = append(deployments, d)
+		}
+	}
+	return deployments
+}
@@ -2,117 +2,486 @@ package releaser
 
 import (
 	"context"
-	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/plumbing"
-	"github.com/google/go-github/v36/github"
-	chartreleaserconfig "github.com/helm/chart-releaser/pkg/config"
-	chartreleasergit "github.com/helm/chart-releaser/pkg/git"
-	chartreleasergithub "github.com/helm/chart-releaser/pkg/github"
-	chartreleaser "github.com/helm/chart-releaser/pkg/releaser"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"text/template"
+	"time"
+
 	"github.com/sirupsen/logrus"
 	"golang.org/x/oauth2"
+	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chartutil"
-	"os"
-	"path"
+	"helm.sh/helm/v3/pkg/provenance"
+	"helm.sh/helm/v3/pkg/repo"
 )
 
-func UpdateReleases(config Configuration, targetDir string, ghToken string) {
-	const pagesBranch = "gh-pages"
-	cwd, _ := os.Getwd()
+const pagesBranch = "gh-pages"
 
-	destRepo := path.Join(cwd, "destrepo")
-	_ = os.RemoveAll(destRepo)
-	_ = os.MkdirAll(destRepo, 0700)
-	defer os.RemoveAll(destRepo)
+// Options configures a Releaser. All interfaces are optional, if they are nil
+// the defaults interacting with github.com and the local disk are used.
+type Options struct {
+	// Config lists the sources and the destination repository
+	Config Configuration
+	// TargetDir is the directory where chart packages and exported charts are stored
+	TargetDir string
+	// CacheDir is the directory where the source repositories are cloned to. The clones
+	// are kept between runs, defaults to a directory in the os temp directory.
+	CacheDir string
+	// WorkDir is the directory in which temporary workspaces are created,
+	// defaults to the os temp directory.
+	WorkDir string
+	// Token is the github token used by the default GitHub and Git implementations. It is only
+	// sent to the host of the destination repository, sources on other hosts need credentials of their own.
+	Token string
+	// App authenticates the default GitHub and Git implementations as installation of a
+	// GitHub App instead of Token. Its tokens are minted by the API of the destination host
+	// and, like Token, only sent to that host.
+	App *GitHubApp
+	// TokenSource provides the tokens of the default GitHub and Git implementations,
+	// it takes precedence over Token and App
+	TokenSource oauth2.TokenSource
+	// Endpoints are the base URLs of the GitHub instance of sources and the destination without
+	// a host, unset endpoints default to github.com
+	Endpoints Endpoints
 
-	logrus.Info("Cloning destrepo ", config.DstCfg.Owner, "/", config.DstCfg.Repo)
-	_, err := git.PlainClone(destRepo, false, &git.CloneOptions{
-		URL:           "https://github.com/" + config.DstCfg.Owner + "/" + config.DstCfg.Repo,
-		ReferenceName: plumbing.NewBranchReferenceName(pagesBranch),
-		SingleBranch:  false,
-	})
+	// Timeout limits the duration of a whole run, runs are not limited if it is zero
+	Timeout time.Duration
+	// RequestTimeout limits the duration of a single request of the default HTTP client, defaults to two minutes
+	RequestTimeout time.Duration
+	// GitTimeout limits the duration of a single clone, fetch or push, defaults to ten minutes
+	GitTimeout time.Duration
+	// Concurrency is the number of sources processed in parallel, defaults to 4
+	Concurrency int
+	// Lint lints every chart before it is packaged by Update, charts with lint errors are not published
+	Lint bool
+	// HTTPClient is used by the default GitHub and Fetcher implementations, defaults
+	// to a client with retries and a response cache in CacheDir
+	HTTPClient *http.Client
+
+	GitHub GitHub
+	// PullRequests proposes index updates of the destination repository, if pull requests are configured
+	PullRequests PullRequests
+	Git          Git
+	Fetcher      Fetcher
+	FileSystem   FileSystem
+	Logger       logrus.FieldLogger
+}
+
+// Releaser collects the charts of the configured sources and releases them in the destination repository
+type Releaser struct {
+	opts  Options
+	log   logrus.FieldLogger
+	hosts map[string]*host
+	// sourceHosts are the hosts of sources with credentials
+	sourceHosts map[string]*host
+
+	// signer signs the chart packages, if signing is configured
+	signer *provenance.Signatory
+	// notesTemplates are the release notes templates by source name
+	notesTemplates map[string]*template.Template
+
+	mu sync.Mutex
+	// repoLocks serialize the access to the clones in the cache directory
+	repoLocks map[string]*sync.Mutex
+}
+
+// New creates a Releaser and fills in the defaults for all unset options
+func New(opts Options) (*Releaser, error) {
+	opts.Endpoints = opts.Endpoints.withDefaults()
+	if opts.TargetDir == "" {
+		opts.TargetDir = "charts"
+	}
+	if opts.CacheDir == "" {
+		opts.CacheDir = filepath.Join(os.TempDir(), "gardener-chart-releaser")
+	}
+	if opts.Logger == nil {
+		opts.Logger = logrus.StandardLogger()
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	if opts.RequestTimeout == 0 {
+		opts.RequestTimeout = 2 * time.Minute
+	}
+	if opts.GitTimeout == 0 {
+		opts.GitTimeout = 10 * time.Minute
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = NewHTTPClient(HTTPOptions{
+			Timeout: opts.RequestTimeout,
+			// owners cannot start with a dot, so this does not clash with the clones
+			CacheDir: filepath.Join(opts.CacheDir, ".http-cache"),
+			Logger:   opts.Logger,
+		})
+	}
+	// the GitHub clients and token sources pick up the http client from the context
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, opts.HTTPClient)
+
+	if opts.TokenSource == nil && opts.App != nil {
+		endpoints := opts.Endpoints
+		if hostKey(opts.Config.DstCfg.Host) != "" {
+			endpoints = EndpointsForHost(opts.Config.DstCfg.Host)
+		}
+		ts, err := NewAppTokenSource(ctx, *opts.App, endpoints)
+		if err != nil {
+			return nil, err
+		}
+		opts.TokenSource = ts
+	}
+	if opts.TokenSource == nil && opts.Token != "" {
+		opts.TokenSource = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: opts.Token})
+	}
+	if opts.GitHub == nil || opts.PullRequests == nil {
+		// the token is issued for the host of the destination, it is not sent to other hosts
+		ts := opts.TokenSource
+		if hostKey(opts.Config.DstCfg.Host) != "" {
+			ts = nil
+		}
+		client, err := NewGitHubClientFromTokenSource(ctx, ts, opts.Endpoints)
+		if err != nil {
+			return nil, fmt.Errorf("creating GitHub client: %w", err)
+		}
+		if opts.GitHub == nil {
+			opts.GitHub = client.Repositories
+		}
+		if opts.PullRequests == nil {
+			opts.PullRequests = NewPullRequests(client)
+		}
+	}
+	if opts.Git == nil && opts.TokenSource != nil {
+		opts.Git = NewGitWithTokenSource(opts.TokenSource)
+	} else if opts.Git == nil {
+		opts.Git = NewGit(opts.Token)
+	}
+	if opts.Fetcher == nil {
+		opts.Fetcher = NewFetcher(opts.HTTPClient)
+	}
+	if opts.FileSystem == nil {
+		opts.FileSystem = OSFileSystem{}
+	}
+	signer, err := newSignatory(opts.Config.Signing)
+	if err != nil {
+		return nil, err
+	}
+	notesTemplates, err := loadReleaseNotesTemplates(opts.Config)
 	if err != nil {
-		logrus.Error("Error during cloning of destination Repository: ", err)
-		return
-	}
-
-	// get a *github.Client	for the github token
-	// this client will be used for interacting with the github api
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: ghToken},
-	)
-	tokenClient := oauth2.NewClient(context.Background(), ts)
-	client := github.NewClient(tokenClient)
-
-	indexPath := path.Join(destRepo, "index.yaml")
-
-	// main loop over all items in the config file
-	for _, cfg := range config.SrcCfg {
-		versionsToRelease, _ := getReleasesToTrack(cfg, config.DstCfg, client, indexPath)
-		for _, v := range versionsToRelease {
-			cfg.Version = v.Original()
-			topLevelChart, err := getTopLevelChart(cfg, client)
-			if err != nil {
-				logrus.Warn("Did not save chart due to error", err)
-			} else {
-				chartutil.Save(&topLevelChart, targetDir)
-			}
-		}
-	}
-
-	// prepare the chart-releaser configuration
-	chartrelcfg := chartreleaserconfig.Options{
-		Owner:               config.DstCfg.Owner,
-		GitRepo:             config.DstCfg.Repo,
-		ChartsRepo:          config.DstCfg.Repo,
-		IndexPath:           indexPath,
-		PagesIndexPath:      "index.yaml",
-		PagesBranch:         pagesBranch,
-		Remote:              "origin",
-		PackagePath:         path.Join(cwd, targetDir),
-		Sign:                false,
-		Token:               ghToken,
-		Commit:              "",
-		Push:                true,
-		PR:                  false,
-		SkipExisting:        true,
-		ReleaseNameTemplate: "{{ .Name }}-{{ .Version }}",
-		ReleaseNotesFile:    "RELEASE.md",
-	}
-
-	// define the chart releaser
-	gh := chartreleasergithub.NewClient(chartrelcfg.Owner, chartrelcfg.GitRepo, ghToken, "https://api.github.com/", "https://uploads.github.com/")
-	releaser := chartreleaser.NewReleaser(&chartrelcfg, gh, &chartreleasergit.Git{})
-
-	logrus.Info("Creating releases")
-	err = releaser.CreateReleases()
-
-	logrus.Info("Updating index")
-	// chart-releaser assumes its working directory is the destination repo
-	os.Chdir(destRepo)
-	_, err = releaser.UpdateIndexFile()
-	os.Chdir(cwd)
+		return nil, err
+	}
+	if err := validateCompat(opts.Config); err != nil {
+		return nil, err
+	}
+	r := &Releaser{
+		opts:           opts,
+		log:            opts.Logger,
+		signer:         signer,
+		notesTemplates: notesTemplates,
+		repoLocks:      map[string]*sync.Mutex{},
+	}
+	if err := r.initHosts(ctx); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Config returns the configuration the releaser was created with
+func (r *Releaser) Config() Configuration {
+	return r.opts.Config
+}
+
+// UpdateReleases creates releases in the destination repository for all upstream versions
+// which have not been released yet and updates the index.
+//
+// Deprecated: Use New and Releaser.Update instead.
+func UpdateReleases(config Configuration, targetDir string, ghToken string) (*Report, error) {
+	r, err := New(Options{Config: config, TargetDir: targetDir, Token: ghToken})
+	if err != nil {
+		return newReport("update"), err
+	}
+	return r.Update(context.Background())
 }
 
 // ExportCharts Exports the configured charts to a directory
-func ExportCharts(config Configuration, targetDir string, ghToken string) {
-
-	// get a *github.Client	for the github token
-	// this client will be used for interacting with the github api
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: ghToken},
-	)
-	tokenClient := oauth2.NewClient(context.Background(), ts)
-	client := github.NewClient(tokenClient)
-
-	// main loop over all items in the config file
-	for _, cfg := range config.SrcCfg {
-		topLevelChart, err := getTopLevelChart(cfg, client)
+//
+// Deprecated: Use New and Releaser.Export instead.
+func ExportCharts(config Configuration, targetDir string, ghToken string) (*Report, error) {
+	r, err := New(Options{Config: config, TargetDir: targetDir, Token: ghToken})
+	if err != nil {
+		return newReport("export"), err
+	}
+	return r.Export(context.Background())
+}
+
+// Update creates releases in the destination repository for all upstream versions
+// which have not been released yet and updates the index. Charts which failed are collected
+// and returned as Errors, while failures of the destination repository abort the run.
+// The returned report is never nil and describes what happened during the run.
+func (r *Releaser) Update(ctx context.Context) (*Report, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	report := newReport("update")
+	err := r.update(ctx, report)
+	report.finish(err)
+	return report, err
+}
+
+func (r *Releaser) update(ctx context.Context, report *Report) error {
+	destRepo, err := r.opts.FileSystem.MkdirTemp(r.opts.WorkDir, "destrepo-")
+	if err != nil {
+		return err
+	}
+	defer r.opts.FileSystem.RemoveAll(destRepo)
+
+	err = r.cloneDestinationRepo(ctx, destRepo)
+	if err != nil {
+		return fmt.Errorf("cloning destination repository: %w", err)
+	}
+
+	index, err := r.loadIndex(filepath.Join(destRepo, "index.yaml"))
+	if err != nil {
+		return fmt.Errorf("reading index of destination repository: %w", err)
+	}
+	// versions proposed by open index pull requests are not packaged again
+	pending, err := r.pendingIndexUpdates(ctx)
+	if err != nil {
+		return err
+	}
+
+	// sources are packaged in parallel, each one only touches its own entries of
+	// the result slices, so that the order of the configuration is kept
+	sourceReports := make([]*SourceReport, len(r.opts.Config.SrcCfg))
+	for i, cfg := range r.opts.Config.SrcCfg {
+		sourceReports[i] = report.addSource(cfg)
+	}
+	sourceErrs := make([]Errors, len(r.opts.Config.SrcCfg))
+	sourcePackages := make([][]*chartPackage, len(r.opts.Config.SrcCfg))
+	r.forEachSource(ctx, func(i int, cfg SrcConfiguration) {
+		sourcePackages[i], sourceErrs[i] = r.packageSource(ctx, cfg, index, pending, sourceReports[i])
+	})
+	// nothing is published after an interruption
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("update interrupted before publishing: %w", err)
+	}
+
+	var errs Errors
+	var packages []*chartPackage
+	for i := range r.opts.Config.SrcCfg {
+		errs = append(errs, sourceErrs[i]...)
+		packages = append(packages, sourcePackages[i]...)
+	}
+
+	if len(packages) == 0 {
+		r.log.Info("No new releases to create")
+		return errs.ErrorOrNil()
+	}
+
+	// the packaged versions are only released, if publishing succeeds
+	report.PullRequestURL, err = r.publish(ctx, destRepo, index, packages)
+	for _, pkg := range packages {
 		if err != nil {
-			logrus.Warn("Did not save chart due to error", err)
+			pkg.report.Outcome = OutcomeFailed
+			pkg.report.Error = err.Error()
+			pkg.report.ReleaseURL = ""
 		} else {
-			chartutil.SaveDir(&topLevelChart, targetDir)
+			pkg.report.Outcome = OutcomeReleased
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// packageSource packages all versions of a source which have not been released yet and are not
+// pending in an open index pull request
+func (r *Releaser) packageSource(ctx context.Context, cfg SrcConfiguration, index *repo.IndexFile, pending map[string]string, sourceReport *SourceReport) ([]*chartPackage, Errors) {
+	versionsToRelease, err := r.getReleasesToTrack(ctx, cfg, index)
+	if err != nil {
+		r.log.Error(err)
+		sourceReport.Error = err.Error()
+		return nil, Errors{&SourceError{Source: cfg.Name, Err: err}}
+	}
+
+	var errs Errors
+	var packages []*chartPackage
+	// the values of each version are compared to the previous version, which is either
+	// packaged in this run or has been published before
+	var packaged []*chartValues
+	for _, v := range versionsToRelease {
+		// the pull requests list the chart versions, which keep the format of the upstream tag
+		if url, ok := pending[cfg.Name+"-"+chartVersion(v.Original())]; ok {
+			r.log.Info(cfg.Name, "-", chartVersion(v.Original()), " is already proposed in ", url)
+			continue
+		}
+		cfg.Version = v.Original()
+		versionReport := &VersionReport{Version: cfg.Version}
+		sourceReport.Versions = append(sourceReport.Versions, versionReport)
+		start := time.Now()
+
+		previous, err := r.previousValues(ctx, index, cfg.Name, v, packaged)
+		if err != nil {
+			r.log.Warn("Not comparing values of ", cfg.Name, " ", cfg.Version, " to the previous version: ", err)
 		}
+		pkg, err := r.packageChart(ctx, cfg, previous, versionReport)
+		versionReport.Duration = time.Since(start)
+		if err != nil {
+			r.log.Error("Did not save chart due to error: ", err)
+			errs = append(errs, &SourceError{Source: cfg.Name, Version: cfg.Version, Err: err})
+			versionReport.Outcome = OutcomeFailed
+			versionReport.Error = err.Error()
+			continue
+		}
+		packages = append(packages, pkg)
+		packaged = append(packaged, pkg.values)
 	}
+	return packages, errs
+}
 
+// withTimeout limits the duration of a run to Options.Timeout
+func (r *Releaser) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.opts.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, r.opts.Timeout)
+}
+
+// withGitTimeout limits the duration of a single git operation to Options.GitTimeout
+func (r *Releaser) withGitTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, r.opts.GitTimeout)
+}
+
+// chartPackage is a chart which was packaged during an update run and is ready to be published
+type chartPackage struct {
+	path string
+	// provPath is the path of the provenance file, it is empty if signing is disabled
+	provPath string
+	chart    *chart.Chart
+	// notes are the notes of the GitHub release
+	notes string
+	// values are the flattened default values of the chart
+	values *chartValues
+	report *VersionReport
+}
+
+// packageChart builds the top level chart for a source version and saves it as package in the target directory.
+// Its values are compared to the values of the previous version and checked for breaking changes, if there is one.
+func (r *Releaser) packageChart(ctx context.Context, cfg SrcConfiguration, previous *chartValues, versionReport *VersionReport) (*chartPackage, error) {
+	commit, commitDate, err := r.resolveCommit(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	versionReport.Commit = commit
+
+	topLevelChart, err := r.getTopLevelChart(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	values, err := newChartValues(&topLevelChart)
+	if err != nil {
+		return nil, err
+	}
+	if r.opts.Lint {
+		versionReport.Lint, err = r.lintChart(&topLevelChart)
+		if err != nil {
+			return nil, err
+		}
+		if err := lintErrors(versionReport.Lint); err != nil {
+			return nil, err
+		}
+	}
+	if previous != nil {
+		versionReport.ValuesDiff = diffValues(previous, values)
+		if err := r.applyCompat(cfg, previous, values, versionReport); err != nil {
+			return nil, err
+		}
+	}
+
+	packagePath, err := r.savePackage(&topLevelChart, r.opts.TargetDir, commitDate)
+	if err != nil {
+		return nil, err
+	}
+	versionReport.Digest, err = provenance.DigestFile(packagePath)
+	if err != nil {
+		return nil, err
+	}
+	pkg := &chartPackage{
+		path:   packagePath,
+		chart:  &topLevelChart,
+		values: values,
+		report: versionReport,
+	}
+	if r.signer != nil {
+		pkg.provPath, err = r.signPackage(packagePath)
+		if err != nil {
+			return nil, err
+		}
+		versionReport.Signed = true
+	}
+	pkg.notes, err = r.renderReleaseNotes(ctx, cfg, pkg)
+	if err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+// Export exports the configured charts to the target directory.
+// The returned report is never nil and describes what happened during the run.
+func (r *Releaser) Export(ctx context.Context) (*Report, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	report := newReport("export")
+
+	versionReports := make([]*VersionReport, len(r.opts.Config.SrcCfg))
+	for i, cfg := range r.opts.Config.SrcCfg {
+		versionReports[i] = &VersionReport{Version: cfg.Version}
+		report.addSource(cfg).Versions = []*VersionReport{versionReports[i]}
+	}
+	sourceErrs := make([]error, len(r.opts.Config.SrcCfg))
+	r.forEachSource(ctx, func(i int, cfg SrcConfiguration) {
+		versionReport := versionReports[i]
+		start := time.Now()
+
+		err := r.exportChart(ctx, cfg, versionReport)
+		versionReport.Duration = time.Since(start)
+		if err != nil {
+			r.log.Error("Did not save chart due to error: ", err)
+			sourceErrs[i] = &SourceError{Source: cfg.Name, Version: cfg.Version, Err: err}
+			versionReport.Outcome = OutcomeFailed
+			versionReport.Error = err.Error()
+			return
+		}
+		versionReport.Outcome = OutcomeExported
+	})
+
+	var errs Errors
+	for _, err := range sourceErrs {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	err := errs.ErrorOrNil()
+	if ctx.Err() != nil {
+		err = fmt.Errorf("export interrupted: %w", ctx.Err())
+	}
+	report.finish(err)
+	return report, err
+}
+
+// exportChart builds the top level chart for a source version and saves it unpacked in the target directory
+func (r *Releaser) exportChart(ctx context.Context, cfg SrcConfiguration, versionReport *VersionReport) error {
+	commit, _, err := r.resolveCommit(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	versionReport.Commit = commit
+
+	topLevelChart, err := r.getTopLevelChart(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	return chartutil.SaveDir(&topLevelChart, r.opts.TargetDir)
 }
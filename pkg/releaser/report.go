@@ -0,0 +1,162 @@
+package releaser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Outcome describes what happened to a version of a source during a run
+type Outcome string
+
+const (
+	OutcomeReleased Outcome = "released"
+	OutcomeExported Outcome = "exported"
+	OutcomeFailed   Outcome = "failed"
+)
+
+// Report is a machine-readable summary of an update or export run
+type Report struct {
+	Command    string          `json:"command"`
+	StartedAt  time.Time       `json:"startedAt"`
+	FinishedAt time.Time       `json:"finishedAt"`
+	Sources    []*SourceReport `json:"sources"`
+	// PullRequestURL is the pull request proposing the index update, if pull requests are configured
+	PullRequestURL string `json:"pullRequestURL,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// SourceReport lists the versions of a source which were attempted during a run
+type SourceReport struct {
+	Name     string           `json:"name"`
+	Repo     string           `json:"repo"`
+	Versions []*VersionReport `json:"versions"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// VersionReport describes the outcome for a single version of a source
+type VersionReport struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit,omitempty"`
+	Digest  string `json:"digest,omitempty"`
+	Signed  bool   `json:"signed,omitempty"`
+	// ValuesDiff compares the default values to the previous version of the chart
+	ValuesDiff *ValuesDiff `json:"valuesDiff,omitempty"`
+	// Lint are the findings of linting the chart, if linting is enabled
+	Lint []LintMessage `json:"lint,omitempty"`
+	// Compat classifies the changes compared to the previous version of the chart
+	Compat     *CompatReport `json:"compat,omitempty"`
+	ReleaseURL string        `json:"releaseURL,omitempty"`
+	Duration   time.Duration `json:"-"`
+	Outcome    Outcome       `json:"outcome"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// MarshalJSON adds the duration in seconds, which is easier to consume than nanoseconds
+func (v *VersionReport) MarshalJSON() ([]byte, error) {
+	type versionReport VersionReport
+	return json.Marshal(struct {
+		*versionReport
+		DurationSeconds float64 `json:"durationSeconds"`
+	}{(*versionReport)(v), v.Duration.Seconds()})
+}
+
+func newReport(command string) *Report {
+	return &Report{
+		Command:   command,
+		StartedAt: time.Now(),
+	}
+}
+
+func (r *Report) addSource(cfg SrcConfiguration) *SourceReport {
+	s := &SourceReport{
+		Name:     cfg.Name,
+		Repo:     cfg.Repo,
+		Versions: []*VersionReport{},
+	}
+	r.Sources = append(r.Sources, s)
+	return s
+}
+
+// finish records the end of the run and the error which aborted it, if any
+func (r *Report) finish(err error) {
+	r.FinishedAt = time.Now()
+	if _, ok := err.(Errors); err != nil && !ok {
+		r.Error = err.Error()
+	}
+}
+
+// WriteJSON writes the report as JSON to path
+func (r *Report) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// WriteMarkdown writes the report as Markdown to path
+func (r *Report) WriteMarkdown(path string) error {
+	return os.WriteFile(path, []byte(r.Markdown()), 0644)
+}
+
+// Markdown renders the report as Markdown, e.g. for a job summary
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# gardener-chart-releaser %s\n\n", r.Command)
+	fmt.Fprintf(&b, "Started %s, took %s.\n\n", r.StartedAt.UTC().Format(time.RFC3339), r.FinishedAt.Sub(r.StartedAt).Round(time.Second))
+	if r.Error != "" {
+		fmt.Fprintf(&b, "**Run failed:** %s\n\n", markdownEscape(r.Error))
+	}
+	if r.PullRequestURL != "" {
+		fmt.Fprintf(&b, "The index update is proposed in [this pull request](%s).\n\n", r.PullRequestURL)
+	}
+
+	b.WriteString("| Source | Version | Outcome | Commit | Digest | Release | Duration | Error |\n")
+	b.WriteString("|--------|---------|---------|--------|--------|---------|----------|-------|\n")
+	for _, s := range r.Sources {
+		if len(s.Versions) == 0 {
+			outcome := "up to date"
+			if s.Error != "" {
+				outcome = string(OutcomeFailed)
+			}
+			fmt.Fprintf(&b, "| %s | | %s | | | | | %s |\n", s.Name, outcome, markdownEscape(s.Error))
+			continue
+		}
+		for _, v := range s.Versions {
+			release := ""
+			if v.ReleaseURL != "" {
+				release = fmt.Sprintf("[link](%s)", v.ReleaseURL)
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s | %s |\n",
+				s.Name, v.Version, v.Outcome, shorten(v.Commit, 12), shorten(v.Digest, 12), release,
+				v.Duration.Round(time.Millisecond), markdownEscape(v.Error))
+		}
+	}
+
+	for _, s := range r.Sources {
+		for _, v := range s.Versions {
+			if v.ValuesDiff != nil {
+				fmt.Fprintf(&b, "\n## %s %s\n\n%s", s.Name, v.Version, v.ValuesDiff.Markdown())
+			}
+			if v.Compat != nil && len(v.Compat.Breaking) > 0 {
+				fmt.Fprintf(&b, "\n%s", v.Compat.Markdown())
+			}
+		}
+	}
+	return b.String()
+}
+
+func markdownEscape(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+func shorten(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
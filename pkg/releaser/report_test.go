@@ -0,0 +1,73 @@
+package releaser
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReport(t *testing.T) {
+	report := newReport("update")
+	cp := report.addSource(SrcConfiguration{Name: "gardener-controlplane", Repo: "gardener"})
+	cp.Versions = append(cp.Versions, &VersionReport{
+		Version:  "v1.1.0",
+		Commit:   "0123456789abcdef",
+		Digest:   "fedcba9876543210",
+		Duration: 1500 * time.Millisecond,
+		Outcome:  OutcomeFailed,
+		Error:    "values | schema\ninvalid",
+	})
+	report.addSource(SrcConfiguration{Name: "provider-foo", Repo: "gardener-extension-provider-foo"})
+
+	// failed charts are listed per version, only other errors abort the run
+	report.finish(Errors{errors.New("chart failed")})
+	if report.Error != "" || report.FinishedAt.Before(report.StartedAt) {
+		t.Errorf("unexpected run error %q or finish time %s", report.Error, report.FinishedAt)
+	}
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := report.WriteJSON(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Command string `json:"command"`
+		Sources []struct {
+			Name     string           `json:"name"`
+			Versions []map[string]any `json:"versions"`
+		} `json:"sources"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Command != "update" || len(decoded.Sources) != 2 || len(decoded.Sources[1].Versions) != 0 {
+		t.Fatalf("unexpected report %s", data)
+	}
+	v := decoded.Sources[0].Versions[0]
+	if v["durationSeconds"] != 1.5 || v["outcome"] != "failed" || v["commit"] != "0123456789abcdef" {
+		t.Errorf("unexpected version report %v", v)
+	}
+
+	md := report.Markdown()
+	for _, want := range []string{
+		"# gardener-chart-releaser update",
+		"| gardener-controlplane | v1.1.0 | failed | 0123456789ab | fedcba987654 |  | 1.5s | values \\| schema<br>invalid |",
+		"| provider-foo | | up to date | | | | |  |",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("expected %q in Markdown report, got\n%s", want, md)
+		}
+	}
+
+	report.finish(errors.New("cloning destination repository"))
+	if !strings.Contains(report.Markdown(), "**Run failed:** cloning destination repository") {
+		t.Errorf("expected the run error in the Markdown report, got\n%s", report.Markdown())
+	}
+}
@@ -0,0 +1,58 @@
+package releaser
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+
+	"helm.sh/helm/v3/pkg/provenance"
+)
+
+// newSignatory loads the signing key of the configuration, it returns nil if signing is disabled
+func newSignatory(cfg *SigningConfiguration) (*provenance.Signatory, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if cfg.Keyring == "" || cfg.Key == "" {
+		return nil, errors.New("signing requires a key and a keyring")
+	}
+	signer, err := provenance.NewFromKeyring(cfg.Keyring, cfg.Key)
+	if err != nil {
+		return nil, fmt.Errorf("loading signing key %s: %w", cfg.Key, err)
+	}
+	err = signer.DecryptKey(func(name string) ([]byte, error) {
+		if cfg.PassphraseFile == "" {
+			return nil, fmt.Errorf("key %s is encrypted, but no passphrase file is configured", name)
+		}
+		return readPassphrase(cfg.PassphraseFile)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("decrypting signing key %s: %w", cfg.Key, err)
+	}
+	return signer, nil
+}
+
+// readPassphrase returns the first line of a passphrase file
+func readPassphrase(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	passphrase, _, err := bufio.NewReader(f).ReadLine()
+	return passphrase, err
+}
+
+// signPackage writes the provenance file of a chart package next to it and returns its path
+func (r *Releaser) signPackage(packagePath string) (string, error) {
+	// the signatory is shared by all sources, which are packaged concurrently
+	r.mu.Lock()
+	sig, err := r.signer.ClearSign(packagePath)
+	r.mu.Unlock()
+	if err != nil {
+		return "", fmt.Errorf("signing %s: %w", packagePath, err)
+	}
+	provPath := packagePath + ".prov"
+	return provPath, r.opts.FileSystem.WriteFile(provPath, []byte(sig), 0644)
+}
@@ -0,0 +1,129 @@
+package releaser
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// SourceStatus compares the configured version of a source to the upstream and published versions
+type SourceStatus struct {
+	Source string `json:"source"`
+	// Configured is the version of the configuration
+	Configured string `json:"configured"`
+	// LatestUpstream is the latest upstream release, respecting the constraint of the source
+	LatestUpstream string `json:"latestUpstream,omitempty"`
+	// LatestPublished is the latest version of the chart in the index of the destination repository
+	LatestPublished string `json:"latestPublished,omitempty"`
+	// Behind is the number of upstream releases newer than the configured version, up to LatestUpstream
+	Behind int    `json:"behind"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Outdated reports whether newer upstream releases exist or the configured version is not published yet
+func (s *SourceStatus) Outdated() bool {
+	if s.Behind > 0 {
+		return true
+	}
+	configured, err := semver.NewVersion(s.Configured)
+	if err != nil {
+		return false
+	}
+	published, err := semver.NewVersion(s.LatestPublished)
+	return err != nil || published.LessThan(configured)
+}
+
+// Status returns the status of the given sources, or all sources if names is empty.
+// Sources whose upstream releases could not be determined are returned as Errors.
+func (r *Releaser) Status(ctx context.Context, names []string) ([]*SourceStatus, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	destRepo, err := r.opts.FileSystem.MkdirTemp(r.opts.WorkDir, "destrepo-")
+	if err != nil {
+		return nil, err
+	}
+	defer r.opts.FileSystem.RemoveAll(destRepo)
+
+	err = r.cloneDestinationRepo(ctx, destRepo)
+	if err != nil {
+		return nil, fmt.Errorf("cloning destination repository: %w", err)
+	}
+	index, err := r.loadIndex(filepath.Join(destRepo, "index.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("reading index of destination repository: %w", err)
+	}
+
+	statuses := make([]*SourceStatus, len(r.opts.Config.SrcCfg))
+	errs := make([]error, len(r.opts.Config.SrcCfg))
+	r.forEachSource(ctx, func(i int, cfg SrcConfiguration) {
+		if len(names) > 0 && !contains(names, cfg.Name) {
+			return
+		}
+		status := &SourceStatus{Source: cfg.Name, Configured: cfg.Version}
+		statuses[i] = status
+		if published := r.publishedVersions(index, cfg.Name); len(published) > 0 {
+			status.LatestPublished = published[len(published)-1].Original()
+		}
+		errs[i] = r.upstreamStatus(ctx, cfg, status)
+		if errs[i] != nil {
+			status.Error = errs[i].Error()
+			errs[i] = &SourceError{Source: cfg.Name, Version: cfg.Version, Err: errs[i]}
+		}
+	})
+
+	var listed []*SourceStatus
+	for _, status := range statuses {
+		if status != nil {
+			listed = append(listed, status)
+		}
+	}
+	var sourceErrs Errors
+	for _, err := range errs {
+		if err != nil {
+			sourceErrs = append(sourceErrs, err)
+		}
+	}
+	if ctx.Err() != nil {
+		return listed, fmt.Errorf("status interrupted: %w", ctx.Err())
+	}
+	return listed, sourceErrs.ErrorOrNil()
+}
+
+// upstreamStatus sets the latest upstream release of a source and counts the releases the configured version is behind
+func (r *Releaser) upstreamStatus(ctx context.Context, cfg SrcConfiguration, status *SourceStatus) error {
+	latest, err := r.LatestVersion(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	status.LatestUpstream = latest
+	latestVersion, err := semver.NewVersion(latest)
+	if err != nil {
+		return fmt.Errorf("parsing upstream release %s of %s: %w", latest, cfg.Repo, err)
+	}
+	configured, err := semver.NewVersion(cfg.Version)
+	if err != nil {
+		return fmt.Errorf("parsing configured version %s: %w", cfg.Version, err)
+	}
+	if !latestVersion.GreaterThan(configured) {
+		return nil
+	}
+
+	owner, repo := splitRepo(cfg.Repo)
+	releases, err := listAllReleases(ctx, r.sourceHost(cfg).github, owner, repo)
+	if err != nil {
+		return fmt.Errorf("listing upstream releases of %s: %w", cfg.Repo, err)
+	}
+	for _, rel := range releases {
+		if rel.GetDraft() || rel.GetPrerelease() {
+			continue
+		}
+		v, err := semver.NewVersion(rel.GetTagName())
+		if err == nil && v.GreaterThan(configured) && !v.GreaterThan(latestVersion) {
+			status.Behind++
+		}
+	}
+	return nil
+}
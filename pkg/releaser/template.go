@@ -0,0 +1,232 @@
+package releaser
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"sigs.k8s.io/yaml"
+)
+
+// TemplateOptions controls which charts Template renders and how
+type TemplateOptions struct {
+	// Sources restricts the rendering to these sources, all sources are rendered if it is empty
+	Sources []string
+	// ValuesFiles are merged into the default values of the charts, later files take precedence
+	ValuesFiles []string
+	// OutputDir is the directory the manifests are written to
+	OutputDir string
+	// SplitByKind writes the manifests of a chart to one file per kind in a directory of the chart,
+	// instead of one file per chart
+	SplitByKind bool
+	// Namespace is the namespace of the release, defaults to "default"
+	Namespace string
+	// IncludeCRDs adds the CRDs of the crds directories to the manifests
+	IncludeCRDs bool
+}
+
+// TemplateResult lists the files the manifests of a source were written to
+type TemplateResult struct {
+	Source  string   `json:"source"`
+	Version string   `json:"version"`
+	Files   []string `json:"files,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// renderedManifest is a manifest and the template it was rendered from
+type renderedManifest struct {
+	template string
+	kind     string
+	// resource identifies the manifest as "Kind/name" or "Kind/namespace/name"
+	resource string
+	content  string
+}
+
+// Template builds the configured version of the given sources in memory, renders their charts with the
+// given values files, like helm template does, and writes the manifests to the output directory.
+// Sources which could not be rendered are returned as Errors.
+func (r *Releaser) Template(ctx context.Context, opts TemplateOptions) ([]*TemplateResult, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	if opts.Namespace == "" {
+		opts.Namespace = "default"
+	}
+
+	values, err := r.loadValuesFiles(opts.ValuesFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*TemplateResult, len(r.opts.Config.SrcCfg))
+	errs := make([]error, len(r.opts.Config.SrcCfg))
+	r.forEachSource(ctx, func(i int, cfg SrcConfiguration) {
+		if len(opts.Sources) > 0 && !contains(opts.Sources, cfg.Name) {
+			return
+		}
+		results[i] = &TemplateResult{Source: cfg.Name, Version: cfg.Version}
+		c, err := r.getTopLevelChart(ctx, cfg)
+		if err == nil {
+			// the values are modified by rendering, every chart gets a copy of its own
+			var chartValues map[string]any
+			if err = normalizeValues(values, &chartValues); err == nil {
+				results[i].Files, err = r.writeManifests(&c, chartValues, opts)
+			}
+		}
+		if err != nil {
+			results[i].Error = err.Error()
+			errs[i] = &SourceError{Source: cfg.Name, Version: cfg.Version, Err: err}
+		}
+	})
+
+	var rendered []*TemplateResult
+	for _, result := range results {
+		if result != nil {
+			rendered = append(rendered, result)
+		}
+	}
+	var sourceErrs Errors
+	for _, err := range errs {
+		if err != nil {
+			sourceErrs = append(sourceErrs, err)
+		}
+	}
+	if ctx.Err() != nil {
+		return rendered, fmt.Errorf("template interrupted: %w", ctx.Err())
+	}
+	return rendered, sourceErrs.ErrorOrNil()
+}
+
+// writeManifests renders a chart and writes its manifests to "<name>.yaml", or to "<name>/<kind>.yaml"
+// if they are split by kind. It returns the written files.
+func (r *Releaser) writeManifests(c *chart.Chart, values map[string]any, opts TemplateOptions) ([]string, error) {
+	manifests, err := renderManifests(c, values, opts.Namespace, opts.IncludeCRDs)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]*strings.Builder{}
+	var order []string
+	for _, m := range manifests {
+		name := c.Name() + ".yaml"
+		if opts.SplitByKind {
+			name = filepath.Join(c.Name(), strings.ToLower(m.kind)+".yaml")
+		}
+		b, ok := files[name]
+		if !ok {
+			b = &strings.Builder{}
+			files[name] = b
+			order = append(order, name)
+		}
+		fmt.Fprintf(b, "---\n# Source: %s\n%s\n", m.template, m.content)
+	}
+
+	// manifests of an earlier run must not be mixed up with the current ones
+	if err := r.opts.FileSystem.RemoveAll(filepath.Join(opts.OutputDir, c.Name())); err != nil {
+		return nil, err
+	}
+	if err := r.opts.FileSystem.RemoveAll(filepath.Join(opts.OutputDir, c.Name()+".yaml")); err != nil {
+		return nil, err
+	}
+	var written []string
+	for _, name := range order {
+		path := filepath.Join(opts.OutputDir, name)
+		if err := r.opts.FileSystem.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		if err := r.opts.FileSystem.WriteFile(path, []byte(files[name].String()), 0644); err != nil {
+			return nil, err
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+// renderManifests renders a chart like helm template does and returns its non-empty manifests
+// sorted by template. Rendering modifies the chart and the values.
+func renderManifests(c *chart.Chart, values map[string]any, namespace string, includeCRDs bool) ([]renderedManifest, error) {
+	if err := chartutil.ProcessDependencies(c, values); err != nil {
+		return nil, err
+	}
+	renderValues, err := chartutil.ToRenderValues(c, values,
+		chartutil.ReleaseOptions{Name: c.Name(), Namespace: namespace, IsInstall: true}, nil)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := engine.Render(c, renderValues)
+	if err != nil {
+		return nil, fmt.Errorf("rendering chart: %w", err)
+	}
+
+	var manifests []renderedManifest
+	if includeCRDs {
+		for _, crd := range c.CRDObjects() {
+			manifests = append(manifests, splitRendered(crd.Filename, string(crd.File.Data))...)
+		}
+	}
+	for _, template := range sortedKeys(rendered) {
+		if isManifest(template) {
+			manifests = append(manifests, splitRendered(template, rendered[template])...)
+		}
+	}
+	return manifests, nil
+}
+
+// loadValuesFiles reads and merges values files, later files take precedence
+func (r *Releaser) loadValuesFiles(files []string) (map[string]any, error) {
+	values := map[string]any{}
+	for _, file := range files {
+		data, err := r.opts.FileSystem.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("reading values file: %w", err)
+		}
+		fileValues := map[string]any{}
+		if err := yaml.Unmarshal(data, &fileValues); err != nil {
+			return nil, fmt.Errorf("parsing values file %s: %w", file, err)
+		}
+		values = mergeValues(values, fileValues)
+	}
+	return values, nil
+}
+
+// splitRendered splits the output of a template into its non-empty manifests
+func splitRendered(template string, content string) []renderedManifest {
+	var manifests []renderedManifest
+	for _, m := range sortedManifests(content) {
+		if strings.TrimSpace(stripComments(m)) == "" {
+			continue
+		}
+		kind, name, namespace := "unknown", "", ""
+		var obj map[string]any
+		if err := yaml.Unmarshal([]byte(m), &obj); err == nil {
+			if k := nestedString(obj, "kind"); k != "" {
+				kind = k
+			}
+			name, namespace = nestedString(obj, "metadata", "name"), nestedString(obj, "metadata", "namespace")
+		}
+		resource := kind + "/" + name
+		if namespace != "" {
+			resource = kind + "/" + namespace + "/" + name
+		}
+		manifests = append(manifests, renderedManifest{template: template, kind: kind, resource: resource, content: strings.TrimSpace(m)})
+	}
+	return manifests
+}
+
+// mergeValues merges src into dst like helm merges values files, i.e. maps are merged
+// recursively and all other values of src replace the ones of dst
+func mergeValues(dst map[string]any, src map[string]any) map[string]any {
+	for k, v := range src {
+		if m, ok := v.(map[string]any); ok {
+			if existing, ok := dst[k].(map[string]any); ok {
+				dst[k] = mergeValues(existing, m)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+	return dst
+}
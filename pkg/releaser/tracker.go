@@ -2,26 +2,24 @@ package releaser
 
 import (
 	"context"
-	"gopkg.in/yaml.v3"
-	"os"
+	"fmt"
 	"sort"
-	"strings"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/akrennmair/slice"
 	"github.com/google/go-github/v36/github"
-	"github.com/sirupsen/logrus"
+	"helm.sh/helm/v3/pkg/repo"
 )
 
-func getReleasesToTrack(cfg SrcConfiguration, dst DstConfiguration, client *github.Client, indexYamlPath string) ([]*semver.Version, error) {
+func (r *Releaser) getReleasesToTrack(ctx context.Context, cfg SrcConfiguration, index *repo.IndexFile) ([]*semver.Version, error) {
 
-	owner := strings.Split(cfg.Repo, "/")[0]
-	repo := strings.Split(cfg.Repo, "/")[1]
+	owner, repo := splitRepo(cfg.Repo)
 
 	// most probably the last 20 upstreamReleases will contain everything we need
 	// assuming that we do not have more than 5 patch releaeses in 4 consecutive
 	// minor tracks
-	upstreamReleases, _, err := client.Repositories.ListReleases(context.Background(),
+	upstreamReleases, _, err := r.sourceHost(cfg).github.ListReleases(ctx,
 		owner,
 		repo,
 		&github.ListOptions{
@@ -30,36 +28,21 @@ func getReleasesToTrack(cfg SrcConfiguration, dst DstConfiguration, client *gith
 		})
 
 	if err != nil {
-		logrus.Warn(err.Error())
+		return nil, fmt.Errorf("listing upstream releases of %s: %w", cfg.Repo, err)
 	}
 	// get and sort upstream release versions
 	upstreamReleaseVersions := make([]*semver.Version, len(upstreamReleases))
-	for i, r := range upstreamReleases {
-		v, err := semver.NewVersion(r.GetTagName())
+	for i, rel := range upstreamReleases {
+		v, err := semver.NewVersion(rel.GetTagName())
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("parsing upstream release %s of %s: %w", rel.GetTagName(), cfg.Repo, err)
 		}
 		upstreamReleaseVersions[i] = v
 	}
 	sort.Sort(semver.Collection(upstreamReleaseVersions))
 
-	index := make(map[string]any)
-	err = readYamlFile(indexYamlPath, index)
-	if err != nil {
-		return nil, err
-	}
-
-	entries := index["entries"].(map[string]any)[cfg.Name].([]any)
-	ourReleaseVersions := slice.Map(entries, func(e any) *semver.Version {
-		eMap := e.(map[string]any)
-
-		versionString := eMap["version"].(string)
-		version, err := semver.NewVersion(versionString)
-		if err != nil {
-			logrus.Warn(err)
-		}
-		return version
-	})
+	// a chart which has never been released is not listed in the index
+	ourReleaseVersions := r.publishedVersions(index, cfg.Name)
 
 	// Now, filter out all version we have on our side.
 	// If upstreamReleaseVersions is not empty afterwards,
@@ -74,16 +57,70 @@ func getReleasesToTrack(cfg SrcConfiguration, dst DstConfiguration, client *gith
 
 }
 
-func readYamlFile(path string, out any) error {
-	bytes, err := os.ReadFile(path)
-	if err != nil {
-		return err
+// publishedVersions returns the sorted versions of a chart listed in the index
+func (r *Releaser) publishedVersions(index *repo.IndexFile, name string) []*semver.Version {
+	var versions []*semver.Version
+	for _, e := range index.Entries[name] {
+		version, err := semver.NewVersion(e.Version)
+		if err != nil {
+			r.log.Warn("Ignoring invalid version ", e.Version, " of ", name, " in index: ", err)
+			continue
+		}
+		versions = append(versions, version)
 	}
+	sort.Sort(semver.Collection(versions))
+	return versions
+}
+
+// LatestVersion returns the tag of the latest upstream release of a source.
+// If the source has a version constraint, it is the tag of the latest release which is
+// neither a draft nor a prerelease and satisfies the constraint.
+func (r *Releaser) LatestVersion(ctx context.Context, cfg SrcConfiguration) (string, error) {
+	owner, repo := splitRepo(cfg.Repo)
 
-	err = yaml.Unmarshal(bytes, out)
+	if cfg.Constraint != "" {
+		constraint, err := semver.NewConstraint(cfg.Constraint)
+		if err != nil {
+			return "", fmt.Errorf("parsing version constraint of %s: %w", cfg.Name, err)
+		}
+		releases, err := listAllReleases(ctx, r.sourceHost(cfg).github, owner, repo)
+		if err != nil {
+			return "", fmt.Errorf("listing upstream releases of %s: %w", cfg.Repo, err)
+		}
+		var latest *semver.Version
+		var tag string
+		for _, rel := range releases {
+			if rel.GetDraft() || rel.GetPrerelease() {
+				continue
+			}
+			v, err := semver.NewVersion(rel.GetTagName())
+			if err != nil || !constraint.Check(v) {
+				continue
+			}
+			if latest == nil || v.GreaterThan(latest) {
+				latest, tag = v, rel.GetTagName()
+			}
+		}
+		if latest == nil {
+			return "", fmt.Errorf("no release of %s satisfies the constraint %q", cfg.Repo, cfg.Constraint)
+		}
+		return tag, nil
+	}
+
+	latestRelease, _, err := r.sourceHost(cfg).github.GetLatestRelease(ctx, owner, repo)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("fetching latest release of %s: %w", cfg.Repo, err)
 	}
+	return latestRelease.GetTagName(), nil
+}
+
+// resolveCommit returns the upstream commit the version of a source points to and its commit date
+func (r *Releaser) resolveCommit(ctx context.Context, cfg SrcConfiguration) (string, time.Time, error) {
+	owner, repo := splitRepo(cfg.Repo)
 
-	return nil
+	commit, _, err := r.sourceHost(cfg).github.GetCommit(ctx, owner, repo, cfg.Version)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("resolving commit of %s %s: %w", cfg.Repo, cfg.Version, err)
+	}
+	return commit.GetSHA(), commit.GetCommit().GetCommitter().GetDate(), nil
 }
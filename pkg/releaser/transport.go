@@ -0,0 +1,237 @@
+package releaser
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gregjones/httpcache"
+	"github.com/gregjones/httpcache/diskcache"
+	"github.com/peterbourgon/diskv"
+	"github.com/sirupsen/logrus"
+)
+
+// HTTPOptions configures the transport shared by the GitHub clients and the Fetcher
+type HTTPOptions struct {
+	// Timeout limits the duration of every attempt of a request including reading the response,
+	// waiting for retries is not limited by it. Requests are not limited if it is zero.
+	Timeout time.Duration
+	// CacheDir is the directory in which responses are cached between runs, caching is
+	// disabled if it is empty. Cached responses are revalidated with their ETag. Responses are cached
+	// per credentials and the directory is only accessible by the current user.
+	CacheDir string
+	// MaxRetries is the number of retries of failed requests, defaults to 5
+	MaxRetries int
+	// Backoff is the delay before the first retry, it doubles with every retry and defaults to one second
+	Backoff time.Duration
+	// MaxRateLimitWait is the longest time to wait for a rate limit to reset, defaults to one hour
+	MaxRateLimitWait time.Duration
+	Logger           logrus.FieldLogger
+}
+
+// NewHTTPClient returns a client, which retries transient failures with an exponential backoff,
+// waits for exhausted rate limits to reset and caches responses on disk
+func NewHTTPClient(opts HTTPOptions) *http.Client {
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = 5
+	}
+	if opts.Backoff == 0 {
+		opts.Backoff = time.Second
+	}
+	if opts.MaxRateLimitWait == 0 {
+		opts.MaxRateLimitWait = time.Hour
+	}
+	if opts.Logger == nil {
+		opts.Logger = logrus.StandardLogger()
+	}
+
+	var transport http.RoundTripper = &retryTransport{
+		base: http.DefaultTransport,
+		opts: opts,
+		now:  time.Now,
+	}
+	if opts.CacheDir != "" {
+		cache, err := newDiskCache(opts.CacheDir)
+		if err != nil {
+			opts.Logger.Warn("Not caching responses: ", err)
+		} else {
+			transport = &cachingTransport{cache: cache, next: transport, transports: map[string]*httpcache.Transport{}}
+		}
+	}
+	return &http.Client{Transport: transport}
+}
+
+// newDiskCache returns a response cache in dir, which is private to the current user
+func newDiskCache(dir string) (*diskcache.Cache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	// the directory may have been created with default permissions before
+	if err := os.Chmod(dir, 0700); err != nil {
+		return nil, err
+	}
+	return diskcache.NewWithDiskv(diskv.New(diskv.Options{
+		BasePath:     dir,
+		CacheSizeMax: 100 * 1024 * 1024,
+		PathPerm:     0700,
+		FilePerm:     0600,
+	})), nil
+}
+
+// cachingTransport caches responses separately per credentials. httpcache keys the responses by URL only,
+// so a response fetched with one token would otherwise be served to requests with other or no credentials.
+type cachingTransport struct {
+	cache httpcache.Cache
+	next  http.RoundTripper
+
+	mu         sync.Mutex
+	transports map[string]*httpcache.Transport
+}
+
+func (t *cachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// the credentials are identified by a hash, so that they are not written to the disk
+	var prefix string
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		sum := sha256.Sum256([]byte(auth))
+		prefix = hex.EncodeToString(sum[:]) + " "
+	}
+	t.mu.Lock()
+	transport, ok := t.transports[prefix]
+	if !ok {
+		transport = httpcache.NewTransport(prefixedCache{Cache: t.cache, prefix: prefix})
+		transport.Transport = t.next
+		t.transports[prefix] = transport
+	}
+	t.mu.Unlock()
+	return transport.RoundTrip(req)
+}
+
+// prefixedCache prefixes the keys of a cache
+type prefixedCache struct {
+	httpcache.Cache
+	prefix string
+}
+
+func (c prefixedCache) Get(key string) ([]byte, bool) {
+	return c.Cache.Get(c.prefix + key)
+}
+
+func (c prefixedCache) Set(key string, data []byte) {
+	c.Cache.Set(c.prefix+key, data)
+}
+
+func (c prefixedCache) Delete(key string) {
+	c.Cache.Delete(c.prefix + key)
+}
+
+// retryTransport retries requests which failed due to network errors, server errors or rate limits
+type retryTransport struct {
+	base http.RoundTripper
+	opts HTTPOptions
+	now  func() time.Time
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	backoff := t.opts.Backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := t.attempt(req)
+
+		// requests with a body which cannot be replayed, e.g. asset uploads, are not retried
+		if attempt >= t.opts.MaxRetries || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+		wait, retry := t.retryAfter(resp, err, backoff)
+		if !retry {
+			return resp, err
+		}
+		if wait > t.opts.MaxRateLimitWait {
+			t.opts.Logger.Warn("Rate limit of ", req.URL.Host, " resets in ", wait.Round(time.Second), ", not waiting for it")
+			return resp, err
+		}
+		if err != nil {
+			t.opts.Logger.Warn("Request to ", req.URL.Redacted(), " failed, retrying in ", wait.Round(time.Millisecond), ": ", err)
+		} else {
+			t.opts.Logger.Warn("Request to ", req.URL.Redacted(), " returned ", resp.Status, ", retrying in ", wait.Round(time.Millisecond))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+		backoff *= 2
+	}
+}
+
+// attempt sends the request once, limited by the timeout
+func (t *retryTransport) attempt(req *http.Request) (*http.Response, error) {
+	if t.opts.Timeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.opts.Timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases the timeout of a request once its response has been read
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// retryAfter reports whether a request should be retried and how long to wait before
+func (t *retryTransport) retryAfter(resp *http.Response, err error, backoff time.Duration) (time.Duration, bool) {
+	if err != nil {
+		return backoff, true
+	}
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		// secondary rate limits tell how long to wait
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+			if err != nil {
+				return 0, false
+			}
+			wait := time.Unix(reset, 0).Sub(t.now()) + time.Second
+			if wait < 0 {
+				wait = 0
+			}
+			return wait, true
+		}
+		return backoff, resp.StatusCode == http.StatusTooManyRequests
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return backoff, true
+	}
+	return 0, false
+}
@@ -0,0 +1,141 @@
+package releaser
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestHTTPClientRetries(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		switch requests {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case 2:
+			// the rate limit is reset already
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(HTTPOptions{Backoff: time.Millisecond, Logger: testLogger(t)})
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || requests != 3 {
+		t.Errorf("expected success after 3 requests, got %s after %d", resp.Status, requests)
+	}
+}
+
+func TestHTTPClientRateLimitTooLong(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(HTTPOptions{MaxRateLimitWait: time.Minute, Logger: testLogger(t)})
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected the rate limited response, got %s", resp.Status)
+	}
+}
+
+func TestHTTPClientCache(t *testing.T) {
+	full := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if req.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full++
+		w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	for i := 0; i < 2; i++ {
+		// a new client per request, as in subsequent runs
+		client := NewHTTPClient(HTTPOptions{CacheDir: cacheDir, Logger: testLogger(t)})
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "content" {
+			t.Errorf("unexpected body %q", body)
+		}
+	}
+	if full != 1 {
+		t.Errorf("expected one full response, got %d", full)
+	}
+
+	// cached responses of authorized requests are private
+	err := filepath.Walk(cacheDir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().Perm()&0077 != 0 {
+			t.Errorf("%s is accessible by other users: %s", path, info.Mode())
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTTPClientCacheCredentials(t *testing.T) {
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		auth := req.Header.Get("Authorization")
+		requests[auth]++
+		if auth != "token private" {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.Write([]byte("private content"))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(HTTPOptions{CacheDir: t.TempDir(), Logger: testLogger(t)})
+	for _, auth := range []string{"token private", "token private", "", "token other"} {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if auth != "token private" && resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%q: the cached response of other credentials was served: %s", auth, body)
+		}
+	}
+	if requests["token private"] != 1 || requests[""] != 1 || requests["token other"] != 1 {
+		t.Errorf("expected one request per credentials, got %v", requests)
+	}
+}
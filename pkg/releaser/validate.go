@@ -0,0 +1,377 @@
+package releaser
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/xeipuuv/gojsonschema"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// DefaultSchemaURL serves the JSON schemas of the Kubernetes OpenAPI definitions
+	DefaultSchemaURL = "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master"
+	// DefaultKubernetesVersion is the Kubernetes version manifests are validated for, if none is configured
+	DefaultKubernetesVersion = "1.24.0"
+)
+
+// bundledSchemas are the schemas of the Gardener resources charts usually contain,
+// stored as "group/kind_version.json"
+//
+//go:embed schemas
+var bundledSchemas embed.FS
+
+// ValidateOptions controls which charts Validate checks and how
+type ValidateOptions struct {
+	// Sources restricts the validation to these sources, all sources are validated if it is empty
+	Sources []string
+	// KubernetesVersions overrides the Kubernetes versions of the configuration
+	KubernetesVersions []string
+	// Offline only uses bundled and cached schemas, resources without a local schema are skipped.
+	// Only the schemas of Gardener resources are bundled, the schemas of Kubernetes resources have
+	// to be cached by a previous run which was not offline. Kubernetes versions without cached
+	// schemas fail the validation.
+	Offline bool
+}
+
+// ValidationResult lists the invalid fields of the manifests rendered from the configured version of a source
+type ValidationResult struct {
+	Source   string        `json:"source"`
+	Version  string        `json:"version"`
+	Errors   []SchemaError `json:"errors,omitempty"`
+	Warnings []string      `json:"warnings,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// SchemaError is an invalid field of a rendered resource. KubernetesVersion is empty
+// for resources which are validated against schemas independent of the Kubernetes version.
+type SchemaError struct {
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+	Template          string `json:"template"`
+	Resource          string `json:"resource"`
+	Field             string `json:"field"`
+	Message           string `json:"message"`
+}
+
+func (e SchemaError) String() string {
+	s := fmt.Sprintf("%s: %s: %s: %s", e.Template, e.Resource, e.Field, e.Message)
+	if e.KubernetesVersion != "" {
+		s = "[" + e.KubernetesVersion + "] " + s
+	}
+	return s
+}
+
+// Validate builds the configured version of the given sources, renders their charts with all dependencies
+// enabled and validates the manifests against the schemas of the configured Kubernetes versions and the
+// bundled schemas of Gardener resources. Sources with invalid manifests are returned as Errors.
+func (r *Releaser) Validate(ctx context.Context, opts ValidateOptions) ([]*ValidationResult, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	validator := r.newSchemaValidator(opts.Offline)
+	versions := opts.KubernetesVersions
+	if len(versions) == 0 && r.opts.Config.Validation != nil {
+		versions = r.opts.Config.Validation.KubernetesVersions
+	}
+	if len(versions) == 0 {
+		versions = []string{DefaultKubernetesVersion}
+	}
+
+	results := make([]*ValidationResult, len(r.opts.Config.SrcCfg))
+	errs := make([]error, len(r.opts.Config.SrcCfg))
+	r.forEachSource(ctx, func(i int, cfg SrcConfiguration) {
+		if len(opts.Sources) > 0 && !contains(opts.Sources, cfg.Name) {
+			return
+		}
+		result := &ValidationResult{Source: cfg.Name, Version: cfg.Version}
+		results[i] = result
+		c, err := r.getTopLevelChart(ctx, cfg)
+		if err == nil {
+			err = r.validateChart(ctx, validator, &c, versions, result)
+		}
+		if err == nil && len(result.Errors) > 0 {
+			err = fmt.Errorf("%d invalid fields in rendered manifests", len(result.Errors))
+		}
+		if err != nil {
+			result.Error = err.Error()
+			errs[i] = &SourceError{Source: cfg.Name, Version: cfg.Version, Err: err}
+		}
+	})
+
+	var validated []*ValidationResult
+	for _, result := range results {
+		if result != nil {
+			validated = append(validated, result)
+		}
+	}
+	var sourceErrs Errors
+	for _, err := range errs {
+		if err != nil {
+			sourceErrs = append(sourceErrs, err)
+		}
+	}
+	if ctx.Err() != nil {
+		return validated, fmt.Errorf("validation interrupted: %w", ctx.Err())
+	}
+	return validated, sourceErrs.ErrorOrNil()
+}
+
+// validateChart renders a chart with all dependencies enabled and adds the invalid fields of its
+// manifests to the result. Resources without a schema are reported as warnings.
+func (r *Releaser) validateChart(ctx context.Context, validator *schemaValidator, c *chart.Chart, versions []string, result *ValidationResult) error {
+	dir, err := r.opts.FileSystem.MkdirTemp(r.opts.WorkDir, "validate-")
+	if err != nil {
+		return err
+	}
+	defer r.opts.FileSystem.RemoveAll(dir)
+	if err := chartutil.SaveDir(c, dir); err != nil {
+		return err
+	}
+	loaded, err := loader.Load(filepath.Join(dir, c.Name()))
+	if err != nil {
+		return err
+	}
+	manifests, err := renderWithDependencies(loaded, allDependenciesEnabled(loaded))
+	if err != nil {
+		return fmt.Errorf("rendering chart: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for _, template := range sortedKeys(manifests) {
+		if !isManifest(template) {
+			continue
+		}
+		for _, manifest := range sortedManifests(manifests[template]) {
+			var obj map[string]any
+			if err := yaml.Unmarshal([]byte(manifest), &obj); err != nil {
+				result.Errors = append(result.Errors, SchemaError{Template: template, Field: "(root)", Message: err.Error()})
+				continue
+			}
+			if obj == nil {
+				continue
+			}
+			apiVersion, _ := obj["apiVersion"].(string)
+			kind, _ := obj["kind"].(string)
+			name := nestedString(obj, "metadata", "name")
+			resource := kind + "/" + name
+			for _, version := range versions {
+				schema, k8s, err := validator.schema(ctx, version, apiVersion, kind)
+				if err != nil {
+					return err
+				}
+				if schema == nil {
+					warning := fmt.Sprintf("no schema for %s %s", apiVersion, kind)
+					if !seen[warning] {
+						seen[warning] = true
+						result.Warnings = append(result.Warnings, warning)
+					}
+					break
+				}
+				validation, err := schema.Validate(gojsonschema.NewGoLoader(obj))
+				if err != nil {
+					return err
+				}
+				for _, e := range validation.Errors() {
+					schemaErr := SchemaError{Template: template, Resource: resource, Field: e.Field(), Message: e.Description()}
+					if k8s {
+						schemaErr.KubernetesVersion = version
+					}
+					if key := schemaErr.String(); !seen[key] {
+						seen[key] = true
+						result.Errors = append(result.Errors, schemaErr)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// sortedManifests splits the output of a template into its manifests in the order of the template
+func sortedManifests(bigFile string) []string {
+	split := releaseutil.SplitManifests(bigFile)
+	keys := make([]string, 0, len(split))
+	for k := range split {
+		keys = append(keys, k)
+	}
+	// the keys are "manifest-<n>"
+	sort.Slice(keys, func(i, j int) bool {
+		return len(keys[i]) < len(keys[j]) || len(keys[i]) == len(keys[j]) && keys[i] < keys[j]
+	})
+	manifests := make([]string, len(keys))
+	for i, k := range keys {
+		manifests[i] = split[k]
+	}
+	return manifests
+}
+
+// nestedString returns the string at the path of fields in obj or ""
+func nestedString(obj map[string]any, fields ...string) string {
+	var v any = obj
+	for _, f := range fields {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return ""
+		}
+		v = m[f]
+	}
+	s, _ := v.(string)
+	return s
+}
+
+// schemaValidator looks up the JSON schemas of resources in the configured schema directory, the bundled
+// schemas and the schema cache, in this order. Missing schemas of Kubernetes resources are downloaded
+// into the cache, unless it is offline.
+type schemaValidator struct {
+	fetcher  Fetcher
+	url      string
+	dir      string
+	cacheDir string
+	offline  bool
+
+	mu      sync.Mutex
+	schemas map[string]*gojsonschema.Schema
+}
+
+func (r *Releaser) newSchemaValidator(offline bool) *schemaValidator {
+	v := &schemaValidator{
+		fetcher:  r.opts.Fetcher,
+		url:      DefaultSchemaURL,
+		cacheDir: filepath.Join(r.opts.CacheDir, "schemas"),
+		offline:  offline,
+		schemas:  map[string]*gojsonschema.Schema{},
+	}
+	if cfg := r.opts.Config.Validation; cfg != nil {
+		if cfg.SchemaURL != "" {
+			v.url = strings.TrimSuffix(cfg.SchemaURL, "/")
+		}
+		v.dir = cfg.Schemas
+	}
+	return v
+}
+
+// schema returns the schema of a resource and whether it is a schema of the given Kubernetes version,
+// it returns nil if there is no schema
+func (v *schemaValidator) schema(ctx context.Context, k8sVersion string, apiVersion string, kind string) (*gojsonschema.Schema, bool, error) {
+	group, version := "", apiVersion
+	if i := strings.LastIndex(apiVersion, "/"); i >= 0 {
+		group, version = apiVersion[:i], apiVersion[i+1:]
+	}
+	kind = strings.ToLower(kind)
+
+	// schemas of custom resources are independent of the Kubernetes version
+	crdPath := path.Join(group, kind+"_"+version+".json")
+	if group != "" {
+		data, err := v.read(crdPath)
+		if err != nil {
+			return nil, false, err
+		}
+		if data != nil {
+			schema, err := v.compile(crdPath, data)
+			return schema, false, err
+		}
+	}
+	if strings.Contains(group, ".") && !strings.HasSuffix(group, ".k8s.io") {
+		return nil, false, nil
+	}
+
+	name := kind + "-" + version + ".json"
+	if group != "" {
+		name = kind + "-" + strings.Split(group, ".")[0] + "-" + version + ".json"
+	}
+	k8sDir := normalizeKubernetesVersion(k8sVersion) + "-standalone-strict"
+	if v.offline && !v.hasCached(k8sDir) {
+		return nil, true, fmt.Errorf("no schemas of Kubernetes %s are cached, run the validation once without --offline to download them", k8sVersion)
+	}
+	k8sPath := path.Join(k8sDir, name)
+	data, err := v.cached(ctx, k8sPath)
+	if err != nil || data == nil {
+		return nil, true, err
+	}
+	schema, err := v.compile(k8sPath, data)
+	return schema, true, err
+}
+
+// read returns a schema of the schema directory or a bundled schema, or nil if there is none
+func (v *schemaValidator) read(p string) ([]byte, error) {
+	if v.dir != "" {
+		data, err := os.ReadFile(filepath.Join(v.dir, filepath.FromSlash(p)))
+		if err == nil || !errors.Is(err, fs.ErrNotExist) {
+			return data, err
+		}
+	}
+	data, err := bundledSchemas.ReadFile(path.Join("schemas", p))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// hasCached reports whether schemas of the directory have been downloaded into the cache
+func (v *schemaValidator) hasCached(dir string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	_, err := os.Stat(filepath.Join(v.cacheDir, dir))
+	return err == nil
+}
+
+// cached returns a schema of the cache, missing schemas are downloaded unless the validator is offline
+func (v *schemaValidator) cached(ctx context.Context, p string) ([]byte, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	file := filepath.Join(v.cacheDir, filepath.FromSlash(p))
+	data, err := os.ReadFile(file)
+	if err == nil || !errors.Is(err, fs.ErrNotExist) {
+		return data, err
+	}
+	if v.offline {
+		return nil, nil
+	}
+	data, err = v.fetcher.Fetch(ctx, v.url+"/"+p, nil)
+	if errors.Is(err, ErrNotFound) {
+		// most probably the resource is no Kubernetes resource
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("downloading schema, use --offline to validate with local schemas only: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return nil, err
+	}
+	return data, os.WriteFile(file, data, 0644)
+}
+
+func (v *schemaValidator) compile(p string, data []byte) (*gojsonschema.Schema, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if schema, ok := v.schemas[p]; ok {
+		return schema, nil
+	}
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(data))
+	if err != nil {
+		return nil, fmt.Errorf("loading schema %s: %w", p, err)
+	}
+	v.schemas[p] = schema
+	return schema, nil
+}
+
+// normalizeKubernetesVersion returns versions as "v1.24.0", "master" is kept
+func normalizeKubernetesVersion(version string) string {
+	if version == "master" || strings.HasPrefix(version, "v") {
+		return version
+	}
+	return "v" + version
+}
@@ -0,0 +1,68 @@
+package releaser
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// failingFetcher fails all downloads, like a network outage
+type failingFetcher struct{}
+
+func (failingFetcher) Fetch(context.Context, string, *Credentials) ([]byte, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestSchemaValidatorDownloads(t *testing.T) {
+	deployment := DefaultSchemaURL + "/v1.24.0-standalone-strict/deployment-apps-v1.json"
+	for name, tc := range map[string]struct {
+		fetcher Fetcher
+		offline bool
+		cached  []string
+		schema  bool
+		err     bool
+	}{
+		"downloaded":          {fetcher: fakeFetcher{deployment: []byte(`{"type": "object"}`)}, schema: true},
+		"no schema":           {fetcher: fakeFetcher{}},
+		"offline":             {fetcher: failingFetcher{}, offline: true, cached: []string{"deployment-apps-v1.json"}, schema: true},
+		"offline not cached":  {fetcher: failingFetcher{}, offline: true, cached: []string{"service-v1.json"}},
+		"offline empty cache": {fetcher: failingFetcher{}, offline: true, err: true},
+		"unreachable":         {fetcher: failingFetcher{}, err: true},
+		"gardener CRD":        {fetcher: failingFetcher{}, offline: true, schema: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cacheDir := t.TempDir()
+			for _, name := range tc.cached {
+				file := filepath.Join(cacheDir, "v1.24.0-standalone-strict", name)
+				if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(file, []byte(`{"type": "object"}`), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			v := &schemaValidator{
+				fetcher:  tc.fetcher,
+				url:      DefaultSchemaURL,
+				cacheDir: cacheDir,
+				offline:  tc.offline,
+				schemas:  map[string]*gojsonschema.Schema{},
+			}
+			apiVersion, kind := "apps/v1", "Deployment"
+			if name == "gardener CRD" {
+				apiVersion, kind = "core.gardener.cloud/v1beta1", "ControllerRegistration"
+			}
+			schema, _, err := v.schema(context.Background(), "1.24.0", apiVersion, kind)
+			if (err != nil) != tc.err {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if (schema != nil) != tc.schema {
+				t.Errorf("expected a schema: %t, got %v", tc.schema, schema)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package releaser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// ValuesDiff lists the default values which were added, removed or changed compared to a previous version
+type ValuesDiff struct {
+	// From is the version the values are compared to
+	From    string        `json:"from"`
+	Added   []ValueChange `json:"added,omitempty"`
+	Removed []ValueChange `json:"removed,omitempty"`
+	Changed []ValueChange `json:"changed,omitempty"`
+}
+
+// ValueChange is a changed value, its path is the dotted path of keys, e.g. "global.image.tag".
+// Values of subcharts are prefixed with the name of the subchart.
+type ValueChange struct {
+	Path string `json:"path"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+// Empty reports whether no values changed
+func (d *ValuesDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Markdown renders the diff as list, it can be used in release notes templates
+func (d *ValuesDiff) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Changes of the default values since %s:\n\n", d.From)
+	if d.Empty() {
+		b.WriteString("No changes.\n")
+		return b.String()
+	}
+	for _, c := range d.Added {
+		fmt.Fprintf(&b, "- added `%s`: `%s`\n", c.Path, formatValue(c.New))
+	}
+	for _, c := range d.Removed {
+		fmt.Fprintf(&b, "- removed `%s` (was `%s`)\n", c.Path, formatValue(c.Old))
+	}
+	for _, c := range d.Changed {
+		fmt.Fprintf(&b, "- changed `%s`: `%s` → `%s`\n", c.Path, formatValue(c.Old), formatValue(c.New))
+	}
+	return b.String()
+}
+
+func formatValue(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// chartValues are the flattened default values of a chart version
+type chartValues struct {
+	version string
+	values  map[string]any
+	chart   *chart.Chart
+}
+
+func newChartValues(c *chart.Chart) (*chartValues, error) {
+	values, err := flattenChartValues(c)
+	if err != nil {
+		return nil, err
+	}
+	return &chartValues{version: c.Metadata.Version, values: values, chart: c}, nil
+}
+
+// diffValues compares the values of two chart versions
+func diffValues(from *chartValues, to *chartValues) *ValuesDiff {
+	diff := &ValuesDiff{From: from.version}
+	for _, path := range sortedKeys(to.values) {
+		old, ok := from.values[path]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, ValueChange{Path: path, New: to.values[path]})
+		case !reflect.DeepEqual(old, to.values[path]):
+			diff.Changed = append(diff.Changed, ValueChange{Path: path, Old: old, New: to.values[path]})
+		}
+	}
+	for _, path := range sortedKeys(from.values) {
+		if _, ok := to.values[path]; ok {
+			continue
+		}
+		// an empty map or null which is filled in is not removed, its new values are added
+		if isEmptyValue(from.values[path]) && hasPathPrefix(to.values, path+".") {
+			continue
+		}
+		diff.Removed = append(diff.Removed, ValueChange{Path: path, Old: from.values[path]})
+	}
+	return diff
+}
+
+// isEmptyValue reports whether v is null or an empty map
+func isEmptyValue(v any) bool {
+	m, ok := v.(map[string]any)
+	return v == nil || ok && len(m) == 0
+}
+
+func hasPathPrefix(values map[string]any, prefix string) bool {
+	for path := range values {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// flattenChartValues returns the default values of a chart and its subcharts by dotted path.
+// Values of the parent chart override the defaults of a subchart, as in helm.
+func flattenChartValues(c *chart.Chart) (map[string]any, error) {
+	// the values of packaged and freshly built charts differ in their number types,
+	// a JSON round trip normalizes them
+	var values map[string]any
+	if err := normalizeValues(c.Values, &values); err != nil {
+		return nil, err
+	}
+	flat := map[string]any{}
+	flatten(flat, "", values)
+	for _, dep := range c.Dependencies() {
+		depValues, err := flattenChartValues(dep)
+		if err != nil {
+			return nil, err
+		}
+		for path, v := range depValues {
+			if _, ok := flat[dep.Name()+"."+path]; !ok {
+				flat[dep.Name()+"."+path] = v
+			}
+		}
+	}
+	return flat, nil
+}
+
+func normalizeValues(in any, out any) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// flatten adds the leaves of values to flat, lists and empty maps are leaves
+func flatten(flat map[string]any, prefix string, values map[string]any) {
+	for k, v := range values {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		if m, ok := v.(map[string]any); ok && len(m) > 0 {
+			flatten(flat, path, m)
+			continue
+		}
+		flat[path] = v
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// previousValues returns the values of the latest version of a chart which is older than version, taken from
+// the versions packaged in this run and the versions published before. It returns nil if there is no such version.
+func (r *Releaser) previousValues(ctx context.Context, index *repo.IndexFile, name string, version *semver.Version, packaged []*chartValues) (*chartValues, error) {
+	var previous *chartValues
+	var previousVersion *semver.Version
+	for _, p := range packaged {
+		v, err := semver.NewVersion(p.version)
+		if err != nil || !v.LessThan(version) {
+			continue
+		}
+		if previousVersion == nil || v.GreaterThan(previousVersion) {
+			previous, previousVersion = p, v
+		}
+	}
+	if _, publishedVersion := previousEntry(index, name, version); publishedVersion != nil &&
+		(previousVersion == nil || publishedVersion.GreaterThan(previousVersion)) {
+		return r.publishedValues(ctx, index, name, version)
+	}
+	return previous, nil
+}
+
+// previousEntry returns the index entry of the latest version of a chart which is older than version, or nil
+func previousEntry(index *repo.IndexFile, name string, version *semver.Version) (*repo.ChartVersion, *semver.Version) {
+	var previous *repo.ChartVersion
+	var previousVersion *semver.Version
+	for _, e := range index.Entries[name] {
+		v, err := semver.NewVersion(e.Version)
+		if err != nil || !v.LessThan(version) || len(e.URLs) == 0 {
+			continue
+		}
+		if previousVersion == nil || v.GreaterThan(previousVersion) {
+			previous, previousVersion = e, v
+		}
+	}
+	return previous, previousVersion
+}
+
+// publishedValues downloads the latest version of a chart in the index, which is older than version,
+// and returns its values. It returns nil if there is no such version.
+func (r *Releaser) publishedValues(ctx context.Context, index *repo.IndexFile, name string, version *semver.Version) (*chartValues, error) {
+	previous, _ := previousEntry(index, name, version)
+	if previous == nil {
+		return nil, nil
+	}
+
+	creds, err := r.assetCredentials(previous.URLs[0])
+	if err != nil {
+		return nil, err
+	}
+	data, err := r.opts.Fetcher.Fetch(ctx, previous.URLs[0], creds)
+	if err != nil {
+		return nil, err
+	}
+	c, err := loader.LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return newChartValues(c)
+}
@@ -0,0 +1,65 @@
+package releaser
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// fakeFetcher serves fixed responses by URL
+type fakeFetcher map[string][]byte
+
+func (f fakeFetcher) Fetch(_ context.Context, url string, _ *Credentials) ([]byte, error) {
+	data, ok := f[url]
+	if !ok {
+		return nil, fmt.Errorf("downloading %s: %w", url, ErrNotFound)
+	}
+	return data, nil
+}
+
+func TestPreviousValues(t *testing.T) {
+	path, err := chartutil.Save(&chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: "v2", Name: "foo", Version: "1.2.5"},
+		Raw:      []*chart.File{{Name: chartutil.ValuesfileName, Data: []byte("replicas: 1\n")}},
+	}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := New(Options{
+		WorkDir:  t.TempDir(),
+		CacheDir: t.TempDir(),
+		Fetcher:  fakeFetcher{"https://example.com/foo-1.2.5.tgz": data},
+		Logger:   testLogger(t),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := repo.NewIndexFile()
+	addToIndex(index, &chart.Metadata{Name: "foo", Version: "1.2.5"}, "https://example.com/foo-1.2.5.tgz", "", index.Generated)
+	// 1.2.1 is packaged in this run, 1.2.5 has been published before
+	packaged := []*chartValues{{version: "1.2.1"}}
+
+	for version, want := range map[string]string{"1.3.0": "1.2.5", "1.2.3": "1.2.1", "1.2.0": ""} {
+		previous, err := r.previousValues(context.Background(), index, "foo", semver.MustParse(version), packaged)
+		if err != nil {
+			t.Fatalf("%s: %v", version, err)
+		}
+		got := ""
+		if previous != nil {
+			got = previous.version
+		}
+		if got != want {
+			t.Errorf("%s: expected to be compared to %q, got %q", version, want, got)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package releaser
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/provenance"
+	"helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/yaml"
+)
+
+// VerifyOptions controls which published charts Verify checks and how
+type VerifyOptions struct {
+	// Keyring is the path of the keyring with the public keys signatures are checked against.
+	// Without a keyring only the digests are checked.
+	Keyring string
+	// Charts restricts the verification to charts with these names ("name") or
+	// versions ("name-version"), all charts of the index are verified if it is empty
+	Charts []string
+	// Rebuild rebuilds the published versions of configured sources from upstream
+	// and compares them file by file with the published packages
+	Rebuild bool
+}
+
+// ChartVerification is the result of verifying a published chart package
+type ChartVerification struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	URL     string `json:"url"`
+	Digest  string `json:"digest,omitempty"`
+	// SignedBy is the identity of the key the provenance file was signed with
+	SignedBy string `json:"signedBy,omitempty"`
+	// Rebuilt is set if the package was rebuilt from upstream
+	Rebuilt bool `json:"rebuilt,omitempty"`
+	// Differences lists the files which differ between the published and the rebuilt package
+	Differences []string `json:"differences,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// Verify downloads the chart packages listed in the index of the destination repository
+// and checks them against the digests of the index and the release assets of the destination
+// repository. If a keyring is given, the provenance files are checked as well, and with
+// VerifyOptions.Rebuild the packages are compared to packages rebuilt from upstream.
+// Charts which failed the verification are returned as Errors.
+func (r *Releaser) Verify(ctx context.Context, opts VerifyOptions) ([]*ChartVerification, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var signatory *provenance.Signatory
+	if opts.Keyring != "" {
+		var err error
+		signatory, err = provenance.NewFromKeyring(opts.Keyring, "")
+		if err != nil {
+			return nil, fmt.Errorf("loading keyring: %w", err)
+		}
+	} else {
+		r.log.Warn("No keyring given, only checking digests")
+	}
+
+	destRepo, err := r.opts.FileSystem.MkdirTemp(r.opts.WorkDir, "destrepo-")
+	if err != nil {
+		return nil, err
+	}
+	defer r.opts.FileSystem.RemoveAll(destRepo)
+
+	err = r.cloneDestinationRepo(ctx, destRepo)
+	if err != nil {
+		return nil, fmt.Errorf("cloning destination repository: %w", err)
+	}
+	index, err := r.loadIndex(filepath.Join(destRepo, "index.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("reading index of destination repository: %w", err)
+	}
+	index.SortEntries()
+	assets, err := r.releaseAssets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*ChartVerification
+	var errs Errors
+	for _, name := range sortedChartNames(index) {
+		for _, v := range index.Entries[name] {
+			if !selected(opts.Charts, v.Name, v.Version) {
+				continue
+			}
+			var src *SrcConfiguration
+			if opts.Rebuild {
+				src = r.sourceConfig(v.Name)
+				if src == nil {
+					r.log.Warn("No source configured for ", v.Name, ", not rebuilding ", v.Name, "-", v.Version)
+				}
+			}
+			result, err := r.verifyChart(ctx, destRepo, v, signatory, assets, src)
+			results = append(results, result)
+			if err != nil {
+				r.log.Error("Verification of ", v.Name, "-", v.Version, " failed: ", err)
+				result.Error = err.Error()
+				errs = append(errs, &SourceError{Source: v.Name, Version: v.Version, Err: err})
+				continue
+			}
+			r.log.Info("Verified ", v.Name, "-", v.Version)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return results, fmt.Errorf("verification interrupted: %w", err)
+	}
+	return results, errs.ErrorOrNil()
+}
+
+// verifyChart checks the release asset, the digest and the provenance file of a published chart version.
+// If src is not nil, the chart version is rebuilt from upstream and compared to the published package.
+func (r *Releaser) verifyChart(ctx context.Context, workDir string, v *repo.ChartVersion, signatory *provenance.Signatory, assets *releaseAssets, src *SrcConfiguration) (*ChartVerification, error) {
+	result := &ChartVerification{Name: v.Name, Version: v.Version}
+	if len(v.URLs) == 0 {
+		return result, errors.New("index entry has no URL")
+	}
+	result.URL = v.URLs[0]
+	if assets.missing(result.URL) {
+		return result, fmt.Errorf("release asset %s does not exist", result.URL)
+	}
+
+	creds, err := r.assetCredentials(result.URL)
+	if err != nil {
+		return result, err
+	}
+	data, err := r.opts.Fetcher.Fetch(ctx, result.URL, creds)
+	if err != nil {
+		return result, err
+	}
+	result.Digest, err = provenance.Digest(bytes.NewReader(data))
+	if err != nil {
+		return result, err
+	}
+	if result.Digest != v.Digest {
+		return result, fmt.Errorf("digest %s of the package does not match the digest %s of the index", result.Digest, v.Digest)
+	}
+
+	if src != nil {
+		result.Rebuilt = true
+		result.Differences, err = r.rebuildChart(ctx, *src, v.Version, data)
+		if err != nil {
+			return result, fmt.Errorf("rebuilding from upstream: %w", err)
+		}
+		if len(result.Differences) > 0 {
+			return result, fmt.Errorf("package differs from the package rebuilt from upstream: %s", strings.Join(result.Differences, ", "))
+		}
+	}
+
+	if signatory == nil {
+		return result, nil
+	}
+	prov, err := r.opts.Fetcher.Fetch(ctx, result.URL+".prov", creds)
+	if err != nil {
+		return result, fmt.Errorf("fetching provenance file: %w", err)
+	}
+	// the signatory verifies files on disk, the package has to keep its name
+	packagePath := filepath.Join(workDir, filepath.Base(result.URL))
+	if err := r.opts.FileSystem.WriteFile(packagePath, data, 0644); err != nil {
+		return result, err
+	}
+	defer r.opts.FileSystem.RemoveAll(packagePath)
+	if err := r.opts.FileSystem.WriteFile(packagePath+".prov", prov, 0644); err != nil {
+		return result, err
+	}
+	defer r.opts.FileSystem.RemoveAll(packagePath + ".prov")
+
+	verification, err := signatory.Verify(packagePath, packagePath+".prov")
+	if err != nil {
+		return result, fmt.Errorf("verifying signature: %w", err)
+	}
+	for identity := range verification.SignedBy.Identities {
+		result.SignedBy = identity
+		break
+	}
+	return result, nil
+}
+
+// releaseAssets are the download URLs of the chart packages attached to the releases of the destination repository
+type releaseAssets struct {
+	prefix string
+	urls   map[string]bool
+}
+
+// missing reports whether url refers to a release asset of the destination repository which does not exist
+func (a *releaseAssets) missing(url string) bool {
+	return strings.HasPrefix(url, a.prefix) && !a.urls[url]
+}
+
+// releaseAssets lists the chart packages attached to the releases of the destination repository
+func (r *Releaser) releaseAssets(ctx context.Context) (*releaseAssets, error) {
+	dst := r.opts.Config.DstCfg
+	releases, err := listAllReleases(ctx, r.host(dst.Host).github, dst.Owner, dst.Repo)
+	if err != nil {
+		return nil, fmt.Errorf("listing releases of destination repository: %w", err)
+	}
+	assets := &releaseAssets{
+		prefix: r.host(dst.Host).endpoints.releaseDownloadPrefix(dst.Owner + "/" + dst.Repo),
+		urls:   make(map[string]bool),
+	}
+	for _, rel := range releases {
+		for _, asset := range rel.Assets {
+			assets.urls[asset.GetBrowserDownloadURL()] = true
+		}
+	}
+	return assets, nil
+}
+
+// sourceConfig returns the configuration of the source the chart name is built from, or nil
+func (r *Releaser) sourceConfig(name string) *SrcConfiguration {
+	for i := range r.opts.Config.SrcCfg {
+		if r.opts.Config.SrcCfg[i].Name == name {
+			return &r.opts.Config.SrcCfg[i]
+		}
+	}
+	return nil
+}
+
+// rebuildChart builds a chart version from upstream and returns the files which differ from the published package
+func (r *Releaser) rebuildChart(ctx context.Context, cfg SrcConfiguration, version string, published []byte) ([]string, error) {
+	// chart versions are the upstream tags without the "v" prefix
+	if strings.HasPrefix(cfg.Version, "v") {
+		version = "v" + version
+	}
+	cfg.Version = version
+	_, commitDate, err := r.resolveCommit(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	c, err := r.getTopLevelChart(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := r.opts.FileSystem.MkdirTemp(r.opts.WorkDir, "rebuild-")
+	if err != nil {
+		return nil, err
+	}
+	defer r.opts.FileSystem.RemoveAll(dir)
+	packagePath, err := r.savePackage(&c, dir, commitDate)
+	if err != nil {
+		return nil, err
+	}
+	rebuilt, err := r.opts.FileSystem.ReadFile(packagePath)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(published, rebuilt) {
+		return nil, nil
+	}
+	return compareArchives(published, rebuilt)
+}
+
+// compareArchives compares two chart packages file by file and returns the differing files in alphabetical order.
+// Annotations added while publishing are ignored.
+func compareArchives(published []byte, rebuilt []byte) ([]string, error) {
+	publishedFiles, err := archiveFiles(published)
+	if err != nil {
+		return nil, fmt.Errorf("reading published package: %w", err)
+	}
+	rebuiltFiles, err := archiveFiles(rebuilt)
+	if err != nil {
+		return nil, fmt.Errorf("reading rebuilt package: %w", err)
+	}
+
+	var differences []string
+	for _, name := range sortedKeys(mergeFiles(publishedFiles, rebuiltFiles)) {
+		p, inPublished := publishedFiles[name]
+		b, inRebuilt := rebuiltFiles[name]
+		switch {
+		case !inRebuilt:
+			differences = append(differences, name+" (only in published package)")
+		case !inPublished:
+			differences = append(differences, name+" (missing in published package)")
+		case !bytes.Equal(p, b):
+			differences = append(differences, name+" (changed)")
+		}
+	}
+	return differences, nil
+}
+
+// archiveFiles returns the files of a chart package by path, the Chart.yaml of the top level chart
+// without the annotations added while publishing
+func archiveFiles(data []byte) (map[string][]byte, error) {
+	files, err := loader.LoadArchiveFiles(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]byte, len(files))
+	for _, f := range files {
+		result[f.Name] = f.Data
+		if f.Name != "Chart.yaml" {
+			continue
+		}
+		md := new(chart.Metadata)
+		if err := yaml.Unmarshal(f.Data, md); err != nil {
+			return nil, fmt.Errorf("parsing Chart.yaml: %w", err)
+		}
+		delete(md.Annotations, breakingChangesAnnotation)
+		if len(md.Annotations) == 0 {
+			md.Annotations = nil
+		}
+		result[f.Name], err = yaml.Marshal(md)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// mergeFiles returns the union of the files of two packages
+func mergeFiles(a map[string][]byte, b map[string][]byte) map[string]bool {
+	merged := make(map[string]bool, len(a))
+	for name := range a {
+		merged[name] = true
+	}
+	for name := range b {
+		merged[name] = true
+	}
+	return merged
+}
+
+// sortedChartNames returns the names of all charts of the index in alphabetical order
+func sortedChartNames(index *repo.IndexFile) []string {
+	names := make([]string, 0, len(index.Entries))
+	for name := range index.Entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// selected reports whether a chart version matches one of the filters "name" or "name-version"
+func selected(filters []string, name string, version string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, f := range filters {
+		if f == name || f == name+"-"+version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package releaser
+
+import (
+	"context"
+	"sync"
+)
+
+// forEachSource calls fn for all configured sources, at most Options.Concurrency at a time.
+// fn gets the index of the source, so that results can be stored in configuration order.
+// No further sources are started once ctx is done.
+func (r *Releaser) forEachSource(ctx context.Context, fn func(i int, cfg SrcConfiguration)) {
+	sem := make(chan struct{}, r.opts.Concurrency)
+	var wg sync.WaitGroup
+	for i, cfg := range r.opts.Config.SrcCfg {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func(i int, cfg SrcConfiguration) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i, cfg)
+		}(i, cfg)
+	}
+	wg.Wait()
+}
+
+// lockRepo serializes the use of the clone of a repository in the cache directory,
+// as sources sharing a repository check out different tags. Call the returned func to unlock.
+func (r *Releaser) lockRepo(dir string) func() {
+	r.mu.Lock()
+	lock, ok := r.repoLocks[dir]
+	if !ok {
+		lock = &sync.Mutex{}
+		r.repoLocks[dir] = lock
+	}
+	r.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}